@@ -0,0 +1,308 @@
+package gofixedlength
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+)
+
+// UnmarshalBytes works like Unmarshal on a record held in a byte slice. The
+// layout of every type is read once and cached, and numbers and booleans
+// are parsed from data directly, so that records having no text or time
+// fields are decoded without allocations. v must be a pointer to a struct.
+// Types with fields this path does not handle, like lists, fall back to
+// Unmarshal.
+func UnmarshalBytes(data []byte, v interface{}) error {
+	if _, ok := v.(FixedUnmarshaler); ok {
+		return Unmarshal(string(data), v)
+	}
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	val = val.Elem()
+
+	p := planOf(val.Type())
+	if p.err != nil {
+		return p.err
+	}
+	if p.fallback {
+		return Unmarshal(string(data), v)
+	}
+	p.decode(data, val)
+	if p.validate {
+		return validateStruct(val, false)
+	}
+	return nil
+}
+
+// typePlan holds the decoding steps of a struct type, as read from its
+// tags.
+type typePlan struct {
+	fields   []fieldPlan
+	validate bool  // Fields have `validate` or `checkdigit` tags
+	fallback bool  // Fields need Unmarshal
+	err      error // Invalid tag
+}
+
+// fieldPlan holds the decoding of a tagged field.
+type fieldPlan struct {
+	index  int
+	begin  int
+	end    int
+	opts   fieldOptions
+	nested *typePlan // Embedded struct with a range
+}
+
+var planCache sync.Map // reflect.Type to *typePlan
+
+func planOf(t reflect.Type) *typePlan {
+	if p, ok := planCache.Load(t); ok {
+		return p.(*typePlan)
+	}
+	p := &typePlan{validate: hasValidation(t, make(map[reflect.Type]bool))}
+	p.add(t)
+	planCache.Store(t, p)
+	return p
+}
+
+// add adds the tagged fields of t.
+func (p *typePlan) add(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+
+		b, e, cFormat, ok := fixedTag(typeField.Tag)
+		if !ok {
+			continue
+		}
+
+		opts, err := newFieldOptions(typeField, cFormat, nil)
+		if err != nil && p.err == nil {
+			p.err = err
+		}
+		f := fieldPlan{index: i, begin: b, end: e, opts: opts}
+		switch k := typeField.Type.Kind(); {
+		case isTimeType(typeField.Type), isNumberKind(k), k == reflect.String, k == reflect.Bool:
+		case (k == reflect.Struct || k == reflect.Ptr && typeField.Type.Elem().Kind() == reflect.Struct) && typeField.Tag.Get("fixedsplit") == "":
+			sub := typeField.Type
+			if k == reflect.Ptr {
+				sub = sub.Elem()
+			}
+			if reflect.PtrTo(sub).Implements(reflect.TypeOf((*FixedUnmarshaler)(nil)).Elem()) {
+				p.fallback = true
+				continue
+			}
+			f.nested = planOf(sub)
+			if f.nested.fallback {
+				p.fallback = true
+			}
+		default:
+			p.fallback = true
+			continue
+		}
+		p.fields = append(p.fields, f)
+	}
+}
+
+// hasValidation reports whether t has fields with `validate` or
+// `checkdigit` tags, including in its embedded structs.
+func hasValidation(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+		if typeField.Tag.Get("validate") != "" || typeField.Tag.Get("checkdigit") != "" {
+			return true
+		}
+		sub := typeField.Type
+		if sub.Kind() == reflect.Ptr {
+			sub = sub.Elem()
+		}
+		if sub.Kind() == reflect.Struct && !isTimeType(sub) && hasValidation(sub, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// decode stores the fields found in data into val, skipping values which
+// cannot be parsed as Unmarshal does.
+func (p *typePlan) decode(data []byte, val reflect.Value) {
+	for i := range p.fields {
+		f := &p.fields[i]
+		// Sanity check range before dying miserably
+		if f.begin < 0 || f.end > len(data) || f.begin > f.end {
+			continue
+		}
+		s := data[f.begin:f.end]
+		field := val.Field(f.index)
+
+		if f.nested != nil {
+			if field.Kind() == reflect.Ptr {
+				if field.IsNil() {
+					// Initialize pointer to avoid panic
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+				field = field.Elem()
+			}
+			f.nested.decode(s, field)
+			continue
+		}
+		decodeBytes(field, s, f.opts)
+	}
+}
+
+// decodeBytes is the counterpart of decodeValue for byte slices.
+func decodeBytes(field reflect.Value, s []byte, opts fieldOptions) {
+	if isTimeType(field.Type()) {
+		decodeValue(field, string(s), opts)
+		return
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(string(bytes.TrimRight(s, " ")))
+	case reflect.Bool:
+		if v, ok := parseBoolBytes(s); ok {
+			field.SetBool(v)
+		}
+	case reflect.Float32, reflect.Float64:
+		bits := field.Type().Bits()
+		if v, ok := parseFloatBytes(s, bits); ok {
+			field.SetFloat(v)
+		} else if v, err := ParseFloat(string(s), bits); err == nil {
+			field.SetFloat(v)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := parseIntBytes(s, field.Type().Bits()); ok {
+			field.SetInt(v)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, ok := parseUintBytes(s, field.Type().Bits()); ok {
+			field.SetUint(v)
+		}
+	}
+}
+
+// parseBoolBytes accepts the same values as strconv.ParseBool.
+func parseBoolBytes(s []byte) (bool, bool) {
+	switch string(s) {
+	case "1", "t", "T", "TRUE", "true", "True":
+		return true, true
+	case "0", "f", "F", "FALSE", "false", "False":
+		return false, true
+	}
+	return false, false
+}
+
+// parseUintBytes parses a decimal unsigned integer of the given bit size,
+// as strconv.ParseUint does.
+func parseUintBytes(s []byte, bits int) (uint64, bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	max := uint64(1)<<uint(bits) - 1
+	var n uint64
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		if n > (max-uint64(c-'0'))/10 {
+			return 0, false // Overflow
+		}
+		n = n*10 + uint64(c-'0')
+	}
+	return n, true
+}
+
+// parseIntBytes parses a decimal signed integer of the given bit size, as
+// strconv.ParseInt does.
+func parseIntBytes(s []byte, bits int) (int64, bool) {
+	neg := false
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	n, ok := parseUintBytes(s, 64)
+	if !ok {
+		return 0, false
+	}
+	limit := uint64(1) << uint(bits-1)
+	if !neg && n >= limit || neg && n > limit {
+		return 0, false // Overflow
+	}
+	if neg {
+		return -int64(n), true
+	}
+	return int64(n), true
+}
+
+var float64pow10 = [...]float64{1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+	1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22}
+
+// parseFloatBytes parses plain decimal numbers, like "-0012.50", whose
+// digits and decimals fit an exact division, which gives the same result
+// as strconv.ParseFloat. Other numbers return false, to be parsed by
+// ParseFloat.
+func parseFloatBytes(s []byte, bits int) (float64, bool) {
+	neg := false
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+	point := byte('.')
+	if DECIMAL_COMMA {
+		if bytes.IndexByte(s, '.') >= 0 {
+			return 0, false
+		}
+		point = ','
+	}
+
+	var mantissa uint64
+	digits, decimals, seen := 0, -1, false
+	for _, c := range s {
+		switch {
+		case c == point && decimals < 0:
+			decimals = 0
+		case c >= '0' && c <= '9':
+			seen = true
+			if mantissa > 0 || c != '0' {
+				digits++
+			}
+			mantissa = mantissa*10 + uint64(c-'0')
+			if decimals >= 0 {
+				decimals++
+			}
+			if digits > 15 {
+				return 0, false
+			}
+		default:
+			return 0, false
+		}
+	}
+	if !seen {
+		return 0, false
+	}
+	if decimals < 0 {
+		decimals = 0
+	}
+
+	var f float64
+	if bits == 32 {
+		// Exact in float32 too, so that the single rounding is the same
+		if digits > 7 || decimals > 10 {
+			return 0, false
+		}
+		f = float64(float32(mantissa) / float32(float64pow10[decimals]))
+	} else {
+		if decimals >= len(float64pow10) {
+			return 0, false
+		}
+		f = float64(mantissa) / float64pow10[decimals]
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
@@ -0,0 +1,122 @@
+package gofixedlength
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+type bytesNested struct {
+	Branch uint16 `fixed:"0-3"`
+	Number int64  `fixed:"3-8"`
+}
+
+type bytesTest struct {
+	Numbers bytesNumbers `fixed:"0-5"`
+	Amount  float64      `fixed:"5-15"`
+	Rate    float32      `fixed:"15-22"`
+	Flag    bool         `fixed:"22-23"`
+	Account bytesNested  `fixed:"23-31"`
+	Parent  *bytesNested `fixed:"31-39"`
+}
+
+type bytesNumbers struct {
+	Count int8 `fixed:"0-5"`
+}
+
+func TestUnmarshalBytes(t *testing.T) {
+	for _, data := range []string{
+		"00127-0012.50000.12500T00100042000-0007",
+		"  128 12.5e3  x     true  1234567890123",
+		"-0128+0000000001.5    0000-9999",
+		"",
+	} {
+		var fromString, fromBytes bytesTest
+		errString := Unmarshal(data, &fromString)
+		errBytes := UnmarshalBytes([]byte(data), &fromBytes)
+		if errString != errBytes || mustMarshal(t, fromString) != mustMarshal(t, fromBytes) {
+			t.Errorf("%q: decoded as %+v (%v), expected %+v (%v)", data, fromBytes, errBytes, fromString, errString)
+		}
+	}
+
+	// Text, lists and validation use the other paths
+	var out fixedListTest
+	if err := UnmarshalBytes([]byte("A;B;C       00010002003     "), &out); err != nil || len(out.Numbers) != 3 {
+		t.Errorf("Decoded as %+v (%v)", out, err)
+	}
+	var checked checkDigitTest
+	if err := UnmarshalBytes([]byte("4111111111111112011000015"), &checked); err == nil {
+		t.Errorf("Expected validation to fail")
+	}
+	if err := UnmarshalBytes([]byte("1"), checked); err != ErrInvalidTarget {
+		t.Errorf("Expected ErrInvalidTarget, got %v", err)
+	}
+}
+
+func TestParseNumberBytes(t *testing.T) {
+	for _, s := range []string{"0", "-0", "+1", "12.50", "-0012.345", ".5", "5.", "1.2.3", "",
+		"0.1", "123456789.123456", "9007199254740993", "1e5", "0.3333333333333333333333"} {
+		for _, bits := range []int{32, 64} {
+			expected, err := ParseFloat(s, bits)
+			if f, ok := parseFloatBytes([]byte(s), bits); ok && (err != nil || f != expected) {
+				t.Errorf("parseFloatBytes(%q, %d) = %v, expected %v (%v)", s, bits, f, expected, err)
+			}
+		}
+	}
+	for _, s := range []string{"0", "-1", "+127", "128", "-128", "-129", "255", "256", "1a", "", "-"} {
+		for _, bits := range []int{8, 64} {
+			expected, err := ParseInt(s, bits)
+			if n, ok := parseIntBytes([]byte(s), bits); ok != (err == nil) || ok && n != expected {
+				t.Errorf("parseIntBytes(%q, %d) = %d, %v, expected %d (%v)", s, bits, n, ok, expected, err)
+			}
+			expectedUint, err := ParseUint(s, bits)
+			if n, ok := parseUintBytes([]byte(s), bits); ok != (err == nil) || ok && n != expectedUint {
+				t.Errorf("parseUintBytes(%q, %d) = %d, %v, expected %d (%v)", s, bits, n, ok, expectedUint, err)
+			}
+		}
+	}
+}
+
+func TestUnmarshalBytesAllocations(t *testing.T) {
+	data := []byte("00127-0012.50000.12500T00100042000-0007")
+	var out bytesTest
+	UnmarshalBytes(data, &out)
+	if n := testing.AllocsPerRun(100, func() { UnmarshalBytes(data, &out) }); n != 0 {
+		t.Errorf("UnmarshalBytes allocated %v times per record", n)
+	}
+}
+
+func TestFixedDecoder(t *testing.T) {
+	d := NewFixedDecoder(strings.NewReader("00127\r\n\n-0012\n" + strings.Repeat(" ", 5000) + "1\nxyz"))
+	var out bytesNumbers
+	var counts []int8
+	for {
+		err := d.Decode(&out)
+		if err != nil {
+			if err != io.EOF {
+				t.Errorf("Unexpected error %v", err)
+			}
+			break
+		}
+		counts = append(counts, out.Count)
+	}
+	if len(counts) != 4 || counts[0] != 127 || counts[1] != -12 || d.Line() != 5 {
+		t.Errorf("Decoded %v, line %d", counts, d.Line())
+	}
+}
+
+func BenchmarkUnmarshalNumbers(b *testing.B) {
+	data := "00127-0012.50000.12500T00100042000-0007"
+	var out bytesTest
+	for i := 0; i < b.N; i++ {
+		Unmarshal(data, &out)
+	}
+}
+
+func BenchmarkUnmarshalBytes(b *testing.B) {
+	data := []byte("00127-0012.50000.12500T00100042000-0007")
+	var out bytesTest
+	for i := 0; i < b.N; i++ {
+		UnmarshalBytes(data, &out)
+	}
+}
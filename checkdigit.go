@@ -0,0 +1,316 @@
+package gofixedlength
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrCheckDigitInput   = errors.New("Value is not valid input for the check digit algorithm")
+	ErrUnknownCheckDigit = errors.New("Unknown check digit algorithm")
+)
+
+// CheckDigit is an algorithm verifying and computing the check digits of an
+// identifier. Values include their check digits: Compute returns the value
+// having them replaced by the right ones.
+type CheckDigit interface {
+	Verify(s string) bool
+	Compute(s string) (string, error)
+}
+
+// FixedLength is implemented by check digit algorithms for identifiers of a
+// single length, like ABA routing numbers: integer fields without a `fixed`
+// range, as in delimited records, are zero-padded to it.
+type FixedLength interface {
+	Length() int
+}
+
+var (
+	checkDigitsMutex sync.RWMutex
+	checkDigits      = map[string]CheckDigit{
+		"luhn":  luhnCheck{},
+		"aba":   abaCheck{},
+		"iban":  ibanCheck{},
+		"isin":  isinCheck{},
+		"cusip": cusipCheck{},
+		"cpf":   cpfCheck{},
+		"cnpj":  cnpjCheck{},
+	}
+)
+
+// RegisterCheckDigit makes a check digit algorithm available to the
+// `validate:"check=name"` and `checkdigit:"name"` tags. Built-in algorithms
+// are luhn, aba (ABA routing numbers), iban, isin, cusip, cpf and cnpj.
+func RegisterCheckDigit(name string, c CheckDigit) {
+	checkDigitsMutex.Lock()
+	defer checkDigitsMutex.Unlock()
+	checkDigits[name] = c
+}
+
+func lookupCheckDigit(name string) (CheckDigit, error) {
+	checkDigitsMutex.RLock()
+	defer checkDigitsMutex.RUnlock()
+	c, ok := checkDigits[name]
+	if !ok {
+		return nil, ErrUnknownCheckDigit
+	}
+	return c, nil
+}
+
+// applyCheckDigit computes the check digits of s for the algorithm named in
+// a `checkdigit` tag, if any.
+func applyCheckDigit(name string, s string) (string, error) {
+	if name == "" {
+		return s, nil
+	}
+	c, err := lookupCheckDigit(name)
+	if err != nil {
+		return s, err
+	}
+	return c.Compute(s)
+}
+
+// padCheckDigit zero-pads the digits of an integer to width, or if zero to
+// the length of the identifiers of the algorithm named, so that they keep
+// their leading zeros.
+func padCheckDigit(name string, s string, width int) string {
+	if width <= 0 {
+		if c, err := lookupCheckDigit(name); err == nil {
+			if f, ok := c.(FixedLength); ok {
+				width = f.Length()
+			}
+		}
+	}
+	if n := width - len(s); n > 0 {
+		s = strings.Repeat("0", n) + s
+	}
+	return s
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// alphaValue returns the value of a digit or of a letter counting from 10
+// for 'A', or -1.
+func alphaValue(r byte) int {
+	switch {
+	case r >= '0' && r <= '9':
+		return int(r - '0')
+	case r >= 'A' && r <= 'Z':
+		return int(r-'A') + 10
+	}
+	return -1
+}
+
+// luhnDigit returns the Luhn check digit for a string of digits.
+func luhnDigit(payload string) byte {
+	sum := 0
+	double := true // The rightmost payload digit is doubled
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// weightedDigit returns the check digit of a string of digits with the given
+// weights, modulo 11 as done for CPF and CNPJ numbers.
+func weightedDigit(payload string, weights []int) byte {
+	sum := 0
+	for i := range payload {
+		sum += int(payload[i]-'0') * weights[i]
+	}
+	r := sum % 11
+	if r < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - r)
+}
+
+type luhnCheck struct{}
+
+func (luhnCheck) Verify(s string) bool {
+	return len(s) > 1 && isDigits(s) && luhnDigit(s[:len(s)-1]) == s[len(s)-1]
+}
+
+func (luhnCheck) Compute(s string) (string, error) {
+	if len(s) < 2 || !isDigits(s) {
+		return s, ErrCheckDigitInput
+	}
+	return s[:len(s)-1] + string(luhnDigit(s[:len(s)-1])), nil
+}
+
+type abaCheck struct{}
+
+func (abaCheck) Length() int { return 9 }
+
+func (c abaCheck) Verify(s string) bool {
+	computed, err := c.Compute(s)
+	return err == nil && computed == s
+}
+
+func (abaCheck) Compute(s string) (string, error) {
+	if len(s) != 9 || !isDigits(s) {
+		return s, ErrCheckDigitInput
+	}
+	weights := []int{3, 7, 1, 3, 7, 1, 3, 7}
+	sum := 0
+	for i, w := range weights {
+		sum += int(s[i]-'0') * w
+	}
+	return s[:8] + string(byte('0'+(10-sum%10)%10)), nil
+}
+
+type ibanCheck struct{}
+
+// mod97 computes the IBAN remainder of a rearranged IBAN.
+func (ibanCheck) mod97(s string) (int, bool) {
+	r := 0
+	for i := 0; i < len(s); i++ {
+		v := alphaValue(s[i])
+		if v < 0 {
+			return 0, false
+		}
+		if v >= 10 {
+			r = r * 100 % 97
+		} else {
+			r = r * 10 % 97
+		}
+		r = (r + v) % 97
+	}
+	return r, true
+}
+
+func (c ibanCheck) Verify(s string) bool {
+	if len(s) < 5 || len(s) > 34 {
+		return false
+	}
+	r, ok := c.mod97(s[4:] + s[:4])
+	return ok && r == 1
+}
+
+func (c ibanCheck) Compute(s string) (string, error) {
+	if len(s) < 5 || len(s) > 34 {
+		return s, ErrCheckDigitInput
+	}
+	r, ok := c.mod97(s[4:] + s[:2] + "00")
+	if !ok {
+		return s, ErrCheckDigitInput
+	}
+	check := strconv.Itoa(98 - r)
+	if len(check) < 2 {
+		check = "0" + check
+	}
+	return s[:2] + check + s[4:], nil
+}
+
+type isinCheck struct{}
+
+func (c isinCheck) Verify(s string) bool {
+	computed, err := c.Compute(s)
+	return err == nil && computed == s
+}
+
+func (isinCheck) Compute(s string) (string, error) {
+	if len(s) != 12 {
+		return s, ErrCheckDigitInput
+	}
+	var digits strings.Builder
+	for i := 0; i < 11; i++ {
+		v := alphaValue(s[i])
+		if v < 0 {
+			return s, ErrCheckDigitInput
+		}
+		digits.WriteString(strconv.Itoa(v))
+	}
+	return s[:11] + string(luhnDigit(digits.String())), nil
+}
+
+type cusipCheck struct{}
+
+func (cusipCheck) Length() int { return 9 }
+
+func (c cusipCheck) Verify(s string) bool {
+	computed, err := c.Compute(s)
+	return err == nil && computed == s
+}
+
+func (cusipCheck) Compute(s string) (string, error) {
+	if len(s) != 9 {
+		return s, ErrCheckDigitInput
+	}
+	sum := 0
+	for i := 0; i < 8; i++ {
+		v := alphaValue(s[i])
+		switch s[i] {
+		case '*':
+			v = 36
+		case '@':
+			v = 37
+		case '#':
+			v = 38
+		}
+		if v < 0 {
+			return s, ErrCheckDigitInput
+		}
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v/10 + v%10
+	}
+	return s[:8] + string(byte('0'+(10-sum%10)%10)), nil
+}
+
+type cpfCheck struct{}
+
+func (cpfCheck) Length() int { return 11 }
+
+func (c cpfCheck) Verify(s string) bool {
+	computed, err := c.Compute(s)
+	return err == nil && computed == s
+}
+
+func (cpfCheck) Compute(s string) (string, error) {
+	if len(s) != 11 || !isDigits(s) {
+		return s, ErrCheckDigitInput
+	}
+	first := weightedDigit(s[:9], []int{10, 9, 8, 7, 6, 5, 4, 3, 2})
+	second := weightedDigit(s[:9]+string(first), []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2})
+	return s[:9] + string(first) + string(second), nil
+}
+
+type cnpjCheck struct{}
+
+func (cnpjCheck) Length() int { return 14 }
+
+func (c cnpjCheck) Verify(s string) bool {
+	computed, err := c.Compute(s)
+	return err == nil && computed == s
+}
+
+func (cnpjCheck) Compute(s string) (string, error) {
+	if len(s) != 14 || !isDigits(s) {
+		return s, ErrCheckDigitInput
+	}
+	first := weightedDigit(s[:12], []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2})
+	second := weightedDigit(s[:12]+string(first), []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2})
+	return s[:12] + string(first) + string(second), nil
+}
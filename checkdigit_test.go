@@ -0,0 +1,92 @@
+package gofixedlength
+
+import "testing"
+
+var checkDigitTests = []struct {
+	name  string
+	valid string
+	wrong string // Same payload, wrong check digits
+}{
+	{"luhn", "4111111111111111", "4111111111111112"},
+	{"luhn", "79927398713", "79927398710"},
+	{"aba", "011000015", "011000016"},
+	{"aba", "021000021", "021000020"},
+	{"iban", "GB82WEST12345698765432", "GB00WEST12345698765432"},
+	{"iban", "DE89370400440532013000", "DE99370400440532013000"},
+	{"isin", "US0378331005", "US0378331006"},
+	{"cusip", "037833100", "037833109"},
+	{"cpf", "11144477735", "11144477700"},
+	{"cpf", "52998224725", "52998224752"},
+	{"cnpj", "11222333000181", "11222333000118"},
+}
+
+func TestCheckDigits(t *testing.T) {
+	for _, test := range checkDigitTests {
+		c, err := lookupCheckDigit(test.name)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !c.Verify(test.valid) {
+			t.Errorf("%s: %s should be valid", test.name, test.valid)
+		}
+		if c.Verify(test.wrong) {
+			t.Errorf("%s: %s should be invalid", test.name, test.wrong)
+		}
+		if computed, err := c.Compute(test.wrong); err != nil || computed != test.valid {
+			t.Errorf("%s: computed %s (%v) from %s", test.name, computed, err, test.wrong)
+		}
+	}
+	if _, err := lookupCheckDigit("nope"); err != ErrUnknownCheckDigit {
+		t.Errorf("Expected ErrUnknownCheckDigit, got %v", err)
+	}
+}
+
+type checkDigitTest struct {
+	Card    string `fixed:"0-16" checkdigit:"luhn"`
+	Routing int    `fixed:"16-25" checkdigit:"aba"`
+	IBAN    string `csv:"0" validate:"check=iban"`
+}
+
+func TestCheckDigitTags(t *testing.T) {
+	var out checkDigitTest
+	if err := Unmarshal("4111111111111111011000015", &out); err != nil {
+		t.Errorf("Valid record rejected: %v", err)
+	}
+	err := Unmarshal("4111111111111112011000016", &out)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 2 || errs[0].Rule != "check=luhn" || errs[1].Rule != "check=aba" {
+		t.Errorf("Expected two check digit violations, got %v", err)
+	}
+
+	var csvOut checkDigitTest
+	if err := UnmarshalCsv("GB00WEST12345698765432", ",", &csvOut); err == nil {
+		t.Errorf("Expected IBAN check to fail")
+	}
+
+	s, err := Marshal(checkDigitTest{Card: "4111111111111110", Routing: 11000010})
+	if err != nil || s != "4111111111111111011000015" {
+		t.Errorf("Marshalled as '%s' (%v)", s, err)
+	}
+}
+
+type checkDigitCsvTest struct {
+	Routing int    `csv:"0" checkdigit:"aba"`
+	CPF     uint64 `csv:"1" validate:"check=cpf"`
+	Card    string `csv:"2" checkdigit:"luhn"`
+}
+
+func TestCheckDigitLeadingZeros(t *testing.T) {
+	var out checkDigitCsvTest
+	if err := UnmarshalCsv("011000015,01234567890,", ",", &out); err != nil || out.Routing != 11000015 {
+		t.Errorf("Valid record rejected: %+v (%v)", out, err)
+	}
+	s, err := MarshalCsv(checkDigitCsvTest{Routing: 11000010, CPF: 1234567890}, ",")
+	if err != nil || s != "011000015,1234567890," {
+		t.Errorf("Marshalled as '%s' (%v)", s, err)
+	}
+
+	// Blank optional values are left blank
+	s, err = Marshal(checkDigitTest{Routing: 11000010})
+	if err != nil || s != "                011000015" {
+		t.Errorf("Marshalled as '%s' (%v)", s, err)
+	}
+}
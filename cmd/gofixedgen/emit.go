@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of fields the generated code handles.
+const (
+	kindString = "string"
+	kindInt    = "int"
+	kindUint   = "uint"
+	kindFloat  = "float"
+	kindBool   = "bool"
+	kindTime   = "time"
+	kindStruct = "struct" // Embedded struct with generated methods
+)
+
+// genType describes a struct to generate code for.
+type genType struct {
+	Name     string
+	Doc      string // Doc comment, without the comment markers
+	Fields   []genField
+	Length   int  // Record length, if longer than the ranges of Fields
+	Validate bool // Fields have `validate` tags
+}
+
+// genField describes a field with a `fixed` tag.
+type genField struct {
+	Name    string // Field name
+	Type    string // Go type, as written in the struct
+	Kind    string
+	Bits    int  // Bit size of numbers, 0 for int and uint
+	Ptr     bool // Pointer to a time or to a struct
+	Begin   int
+	End     int
+	Format  string
+	TZ      string
+	Comment string
+}
+
+// length returns the length of the records of the type.
+func (t *genType) length() int {
+	length := t.Length
+	for _, f := range t.Fields {
+		if f.End > length {
+			length = f.End
+		}
+	}
+	return length
+}
+
+// skipsMethods tells why the methods of the type cannot be generated, or
+// returns "": fields with a `tz` tag are left to the reflection path.
+func (t *genType) skipsMethods() string {
+	for _, f := range t.Fields {
+		if f.TZ != "" {
+			return fmt.Sprintf("field %s has a tz tag", f.Name)
+		}
+	}
+	return ""
+}
+
+// emitter writes the body of a generated file, collecting its imports.
+type emitter struct {
+	b       bytes.Buffer
+	imports map[string]bool
+	lib     string // Qualifier of the gofixedlength package
+}
+
+// generate writes a formatted Go file holding the structs if structs is
+// set and, if methods is set, their methods.
+func generate(pkg string, types []*genType, structs, methods bool) ([]byte, error) {
+	e := &emitter{imports: make(map[string]bool), lib: "gofixedlength."}
+	if pkg == "gofixedlength" {
+		e.lib = ""
+	}
+	for _, t := range types {
+		if structs {
+			e.writeStruct(t)
+		}
+		if methods && t.skipsMethods() == "" {
+			e.writeMethods(t)
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gofixedgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if len(e.imports) > 0 {
+		// Standard library first, as goimports does
+		var std, other []string
+		for path := range e.imports {
+			if strings.Contains(path, ".") {
+				other = append(other, strconv.Quote(path))
+			} else {
+				std = append(std, strconv.Quote(path))
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(other)
+		b.WriteString("import (\n")
+		b.WriteString(strings.Join(std, "\n"))
+		if len(std) > 0 && len(other) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n" + strings.Join(other, "\n") + "\n)\n\n")
+	}
+	b.Write(e.b.Bytes())
+	return format.Source(b.Bytes())
+}
+
+func (e *emitter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&e.b, format, args...)
+}
+
+func (e *emitter) writeStruct(t *genType) {
+	if t.Doc != "" {
+		e.printf("// %s.\n", t.Doc)
+	}
+	e.printf("type %s struct {\n", t.Name)
+	for _, f := range t.Fields {
+		tag := fmt.Sprintf("%d-%d", f.Begin, f.End)
+		if f.Format != "" {
+			tag += "," + f.Format
+		}
+		tag = "fixed:" + strconv.Quote(tag)
+		if f.TZ != "" {
+			tag += " tz:" + strconv.Quote(f.TZ)
+		}
+		e.printf("%s %s `%s`", f.Name, f.Type, tag)
+		if f.Comment != "" {
+			e.printf(" // %s", f.Comment)
+		}
+		e.printf("\n")
+		if f.Kind == kindTime {
+			e.imports["time"] = true
+		}
+	}
+	e.printf("}\n\n")
+}
+
+func (e *emitter) writeMethods(t *genType) {
+	if e.lib != "" {
+		e.imports["github.com/qrawl/gofixedlength"] = true
+	}
+
+	e.printf("// UnmarshalFixed decodes a record like gofixedlength.Unmarshal, without\n// reflection.\n")
+	e.printf("func (v *%s) UnmarshalFixed(data string) error {\n", t.Name)
+	for _, f := range t.Fields {
+		e.printf("if len(data) >= %d {\n", f.End)
+		e.writeDecode(f, fmt.Sprintf("data[%d:%d]", f.Begin, f.End))
+		e.printf("}\n")
+	}
+	if t.Validate {
+		e.printf("return %sValidate(v)\n}\n\n", e.lib)
+	} else {
+		e.printf("return nil\n}\n\n")
+	}
+
+	e.printf("// MarshalFixed encodes a record like gofixedlength.Marshal, without\n// reflection.\n")
+	e.printf("func (v %s) MarshalFixed() (string, error) {\n", t.Name)
+	if t.Validate {
+		e.printf("if err := %sValidate(v); err != nil {\nreturn \"\", err\n}\n", e.lib)
+	}
+	e.printf("line := make(%sLine, %d)\n", e.lib, t.length())
+	for _, f := range t.Fields {
+		if f.Kind != kindStruct && f.Kind != kindBool {
+			e.printf("var s string\n")
+			break
+		}
+	}
+	for _, f := range t.Fields {
+		switch f.Kind {
+		case kindBool:
+			// Booleans are left blank, as by Marshal
+			continue
+		case kindStruct:
+			e.writeEncodeStruct(f)
+			continue
+		}
+		e.writeEncode(f)
+		e.printf("if err := line.WriteString(s, %d, %d); err != nil {\nreturn line.String(), err\n}\n", f.Begin, f.End)
+	}
+	e.printf("for i, r := range line {\nif r == 0 {\nline[i] = ' '\n}\n}\n")
+	e.printf("return line.String(), nil\n}\n\n")
+}
+
+// writeDecode writes the statements storing the value of the text in
+// field, skipping values which cannot be parsed.
+func (e *emitter) writeDecode(f genField, text string) {
+	target := "v." + f.Name
+	switch f.Kind {
+	case kindString:
+		e.imports["strings"] = true
+		e.printf("%s = %s\n", target, convert(f.Type, "string", fmt.Sprintf("strings.TrimRight(%s, \" \")", text)))
+	case kindInt:
+		e.printf("if n, err := %sParseInt(%s, %d); err == nil {\n%s = %s\n}\n", e.lib, text, f.Bits, target, convert(f.Type, "int64", "n"))
+	case kindUint:
+		e.printf("if n, err := %sParseUint(%s, %d); err == nil {\n%s = %s\n}\n", e.lib, text, f.Bits, target, convert(f.Type, "uint64", "n"))
+	case kindFloat:
+		e.printf("if n, err := %sParseFloat(%s, %d); err == nil {\n%s = %s\n}\n", e.lib, text, f.Bits, target, convert(f.Type, "float64", "n"))
+	case kindBool:
+		e.printf("if x, err := %sParseBool(%s); err == nil {\n%s = x\n}\n", e.lib, text, target)
+	case kindTime:
+		if f.Ptr {
+			e.imports["strings"] = true
+			e.printf("if strings.TrimSpace(%s) == \"\" {\n%s = nil\n} else ", text, target)
+			e.printf("if t, err := %sParseTime(%s, %q, nil); err == nil {\n%s = &t\n}\n", e.lib, text, f.Format, target)
+		} else {
+			e.printf("if t, err := %sParseTime(%s, %q, nil); err == nil {\n%s = t\n}\n", e.lib, text, f.Format, target)
+		}
+	case kindStruct:
+		// Errors of embedded objects are ignored, as by Unmarshal
+		if f.Ptr {
+			e.printf("if %s == nil {\n%s = new(%s)\n}\n", target, target, f.Type)
+		}
+		e.printf("_ = %s.UnmarshalFixed(%s)\n", target, text)
+	}
+}
+
+// writeEncode writes the statements setting s to the text of the field.
+func (e *emitter) writeEncode(f genField) {
+	source := "v." + f.Name
+	width := f.End - f.Begin
+	switch f.Kind {
+	case kindString:
+		e.printf("s = %sFormatString(%s, %d)\n", e.lib, convert("string", f.Type, source), width)
+	case kindInt:
+		e.printf("s = %sFormatInt(%s, %d)\n", e.lib, convert("int64", f.Type, source), width)
+	case kindUint:
+		e.printf("s = %sFormatUint(%s, %d)\n", e.lib, convert("uint64", f.Type, source), width)
+	case kindFloat:
+		e.printf("s = %sFormatFloat(%s, %d, %q, %d)\n", e.lib, convert("float64", f.Type, source), f.Bits, f.Format, width)
+	case kindTime:
+		if f.Ptr {
+			e.printf("s = %sFormatString(\"\", %d)\n", e.lib, width)
+			e.printf("if %s != nil {\ns = %sFormatTime(*%s, %q, nil, %d)\n}\n", source, e.lib, source, f.Format, width)
+		} else {
+			e.printf("s = %sFormatTime(%s, %q, nil, %d)\n", e.lib, source, f.Format, width)
+		}
+	}
+}
+
+// writeEncodeStruct writes the statements marshalling an embedded object
+// into its range, padded with spaces. Nil pointers are skipped.
+func (e *emitter) writeEncodeStruct(f genField) {
+	e.imports["strings"] = true
+	e.imports["unicode/utf8"] = true
+	source := "v." + f.Name
+	width := f.End - f.Begin
+	// Keep sub and err local to the field
+	if f.Ptr {
+		e.printf("if %s != nil {\n", source)
+	} else {
+		e.printf("{\n")
+	}
+	e.printf("sub, err := %s.MarshalFixed()\nif err != nil {\nreturn line.String(), err\n}\n", source)
+	e.printf("if n := utf8.RuneCountInString(sub); n < %d {\nsub += strings.Repeat(\" \", %d-n)\n}\n", width, width)
+	e.printf("if err := line.WriteString(sub, %d, %d); err != nil {\nreturn line.String(), err\n}\n", f.Begin, f.End)
+	e.printf("}\n")
+}
+
+// convert returns the expression converting expr from type from to type to.
+func convert(to, from, expr string) string {
+	if to == from {
+		return expr
+	}
+	return to + "(" + expr + ")"
+}
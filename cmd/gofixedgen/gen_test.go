@@ -0,0 +1,195 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const csvSpec = `name,start,length,type,format
+Account number,1,10,string
+Amount,11,10,float,2
+Due date,21,8,time,20060102
+`
+
+func TestCsvSpec(t *testing.T) {
+	types, err := readCsvSpec(strings.NewReader(csvSpec), "detail", 1)
+	if err != nil {
+		t.Fatalf("readCsvSpec failed: %v", err)
+	}
+	if len(types) != 1 || types[0].Name != "Detail" || len(types[0].Fields) != 3 {
+		t.Fatalf("Read %+v", types)
+	}
+	f := types[0].Fields[2]
+	if f.Name != "DueDate" || f.Begin != 20 || f.End != 28 || f.Kind != kindTime || f.Format != "20060102" {
+		t.Errorf("Read field %+v", f)
+	}
+
+	if _, err := readCsvSpec(strings.NewReader("A,0,2\nB,x,1\n"), "r", 0); err == nil {
+		t.Errorf("Expected an invalid start error")
+	}
+	if _, err := readCsvSpec(strings.NewReader("A,0,2,money\n"), "r", 0); err == nil {
+		t.Errorf("Expected an unknown type error")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	types, err := readJSONSpec(strings.NewReader(`{"records": [
+		{"name": "detail", "begin": 0, "end": 1, "code": "6", "fields": [
+			{"name": "account", "begin": 1, "end": 11},
+			{"name": "count", "begin": 11, "end": 15, "type": "int"},
+			{"name": "paid", "begin": 15, "end": 16, "type": "bool"}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatalf("readJSONSpec failed: %v", err)
+	}
+
+	src, err := generate("payments", types, true, true)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatalf("Generated code does not parse: %v\n%s", err, src)
+	}
+	for _, expected := range []string{
+		"RecordCode string `fixed:\"0-1\"` // Always \"6\"",
+		"Account    string `fixed:\"1-11\"`",
+		"v.Account = strings.TrimRight(data[1:11], \" \")",
+		"v.Count = int(n)",
+		"func (v *Detail) UnmarshalFixed(data string) error {",
+		"gofixedlength.ParseInt(data[11:15], 0)",
+		"func (v Detail) MarshalFixed() (string, error) {",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("Missing %q in:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(string(src), "v.Paid, 1") {
+		t.Errorf("Booleans should be left blank:\n%s", src)
+	}
+
+	if _, err := readJSONSpec(strings.NewReader(`{"records": [
+		{"name": "detail", "begin": 0, "end": 1, "code": "6", "fields": [
+			{"name": "record code", "begin": 1, "end": 2}
+		]}
+	]}`)); err == nil {
+		t.Errorf("Expected an error for a field named RecordCode")
+	}
+}
+
+func TestGenerateTimeZone(t *testing.T) {
+	types, err := readJSONSpec(strings.NewReader(`{"records": [
+		{"name": "detail", "fields": [
+			{"name": "at", "begin": 0, "end": 12, "type": "time", "format": "200601021504", "tz": "Europe/Paris"}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatalf("readJSONSpec failed: %v", err)
+	}
+	if reason := types[0].skipsMethods(); reason != "field At has a tz tag" {
+		t.Errorf("Expected the methods to be skipped, got %q", reason)
+	}
+	src, err := generate("payments", types, true, true)
+	if err != nil || strings.Contains(string(src), "UnmarshalFixed") {
+		t.Errorf("Methods generated for a tz field (%v):\n%s", err, src)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for name, expected := range map[string]string{
+		"account-number": "AccountNumber",
+		"Account.Branch": "AccountBranch",
+		"1st line":       "F1stLine",
+		"--":             "Field",
+	} {
+		if got := goName(name); got != expected {
+			t.Errorf("goName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+const sourceTest = `package records
+
+import "time"
+
+type Common struct {
+	Kind string ` + "`fixed:\"0-1\"`" + `
+}
+
+type Branch struct {
+	Code string ` + "`fixed:\"0-3\"`" + `
+}
+
+type Detail struct {
+	Common
+	Date   time.Time ` + "`fixed:\"1-9,20060102\"`" + `
+	Branch *Branch   ` + "`fixed:\"9-12\"`" + `
+}
+
+type Listed struct {
+	Codes []string ` + "`fixed:\"0-10\" fixedsplit:\";\"`" + `
+}
+
+type Checked struct {
+	Card string ` + "`fixed:\"0-16\" checkdigit:\"luhn\"`" + `
+}
+
+type Meta struct {
+	Source string ` + "`validate:\"required\"`" + `
+}
+
+type Noted struct {
+	Code string ` + "`fixed:\"0-3\"`" + `
+	Info Meta
+}
+`
+
+func TestReadSource(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "records.go")
+	if err := ioutil.WriteFile(filename, []byte(sourceTest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, types, skipped, err := readSource([]string{filename}, nil)
+	if err != nil {
+		t.Fatalf("readSource failed: %v", err)
+	}
+	if pkg != "records" || len(types) != 4 || len(skipped) != 2 {
+		t.Fatalf("Read %s, %+v, skipped %q", pkg, types, skipped)
+	}
+	detail := types[2]
+	if detail.Name != "Detail" || len(detail.Fields) != 2 || detail.Fields[0].Name != "Date" || detail.length() != 12 {
+		t.Errorf("Read %+v", detail)
+	}
+	if f := detail.Fields[1]; f.Kind != kindStruct || !f.Ptr || f.Type != "Branch" {
+		t.Errorf("Read nested field %+v", f)
+	}
+	if noted := types[3]; noted.Name != "Noted" || len(noted.Fields) != 1 || !noted.Validate {
+		t.Errorf("Validation through untagged structs lost in %+v", noted)
+	}
+
+	src, err := generate(pkg, types, false, true)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	for _, expected := range []string{
+		"v.Code = strings.TrimRight(data[0:3], \" \")",
+		"v.Branch = new(Branch)",
+		"sub, err := v.Branch.MarshalFixed()",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("Missing %q in:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(string(src), "type Detail struct") {
+		t.Errorf("Structs should not be written from source")
+	}
+
+	if _, _, _, err := readSource([]string{filename}, []string{"Missing"}); err == nil {
+		t.Errorf("Expected an error for a missing type")
+	}
+}
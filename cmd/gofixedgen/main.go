@@ -0,0 +1,131 @@
+// Command gofixedgen writes Go structs with `fixed` tags from a layout
+// spec, so that ranges are not transcribed by hand:
+//
+//	gofixedgen -pkg payments -o records.go payments.json
+//	gofixedgen -type Detail -base 1 -methods -o detail.go detail.csv
+//
+// A JSON spec is a schema as read by gofixedlength.LoadSchema, and gives a
+// struct for every record type. A CSV spec lists the fields of a single
+// record as name,start,length,type[,format] lines, optionally after a
+// header line; start is zero based unless -base says otherwise.
+//
+// With -methods, UnmarshalFixed and MarshalFixed methods are generated too.
+// They decode and encode records like gofixedlength.Unmarshal and Marshal,
+// without reflection. Records having fields with a time zone get no
+// methods, which is reported.
+//
+// Given Go files instead, gofixedgen writes the methods of the structs
+// they declare with `fixed` tags, or of the ones listed by -types:
+//
+//	//go:generate gofixedgen -o records_fixed.go records.go
+//
+// Unmarshal and Marshal call the generated methods when present. Structs
+// with fields the methods could not handle like the reflection path does,
+// such as lists, `tz` or `checkdigit` tags, are reported and skipped.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	output   = flag.String("o", "", "output file (standard output if empty)")
+	pkgName  = flag.String("pkg", "main", "package name of the generated file")
+	typeName = flag.String("type", "Record", "struct name for a CSV spec")
+	base     = flag.Int("base", 0, "offset of the first column in a CSV spec")
+	methods  = flag.Bool("methods", false, "generate UnmarshalFixed and MarshalFixed methods")
+	typeList = flag.String("types", "", "comma-separated structs to generate methods for, from Go files")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: gofixedgen [flags] spec.json|spec.csv")
+		fmt.Fprintln(os.Stderr, "       gofixedgen [flags] file.go...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	var err error
+	if strings.HasSuffix(flag.Arg(0), ".go") {
+		err = runSource(flag.Args())
+	} else if flag.NArg() == 1 {
+		err = runSpec(flag.Arg(0))
+	} else {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gofixedgen:", err)
+		os.Exit(1)
+	}
+}
+
+// runSpec writes structs, and possibly their methods, from a layout spec.
+func runSpec(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var types []*genType
+	if strings.ToLower(filepath.Ext(filename)) == ".csv" {
+		types, err = readCsvSpec(f, *typeName, *base)
+	} else {
+		types, err = readJSONSpec(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *methods {
+		for _, t := range types {
+			if reason := t.skipsMethods(); reason != "" {
+				fmt.Fprintf(os.Stderr, "gofixedgen: skipping the methods of %s: %s\n", t.Name, reason)
+			}
+		}
+	}
+
+	src, err := generate(*pkgName, types, true, *methods)
+	if err != nil {
+		return err
+	}
+	return writeOutput(src)
+}
+
+// runSource writes the methods of the structs declared in Go files.
+func runSource(filenames []string) error {
+	var names []string
+	if *typeList != "" {
+		names = strings.Split(*typeList, ",")
+	}
+	pkg, types, skipped, err := readSource(filenames, names)
+	if err != nil {
+		return err
+	}
+	for _, s := range skipped {
+		fmt.Fprintln(os.Stderr, "gofixedgen: skipping", s)
+	}
+
+	src, err := generate(pkg, types, false, true)
+	if err != nil {
+		return err
+	}
+	return writeOutput(src)
+}
+
+func writeOutput(src []byte) error {
+	if *output == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*output, src, 0644)
+}
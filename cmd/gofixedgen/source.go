@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/qrawl/gofixedlength/internal/fixedtag"
+)
+
+// sourcePackage holds the struct types declared in a set of Go files.
+type sourcePackage struct {
+	name    string
+	structs map[string]*ast.StructType
+	types   map[string]*genType // Types resolved so far, nil if unsupported
+	reasons map[string]string   // Why a type is unsupported
+}
+
+// readSource parses Go files and returns the types having `fixed` tags,
+// or the named ones if names is not empty, along with the types they
+// nest. Types the generated code cannot handle with the same semantics
+// as the reflection path are left out and reported in skipped.
+func readSource(filenames []string, names []string) (pkg string, types []*genType, skipped []string, err error) {
+	fset := token.NewFileSet()
+	p := &sourcePackage{
+		structs: make(map[string]*ast.StructType),
+		types:   make(map[string]*genType),
+		reasons: make(map[string]string),
+	}
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(fset, filename, nil, 0)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if p.name != "" && p.name != file.Name.Name {
+			return "", nil, nil, fmt.Errorf("%s: package %s, expected %s", filename, file.Name.Name, p.name)
+		}
+		p.name = file.Name.Name
+		ast.Inspect(file, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					p.structs[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+
+	if len(names) == 0 {
+		for name := range p.structs {
+			if p.hasFixedTags(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := p.structs[name]; !ok {
+			return "", nil, nil, fmt.Errorf("type %s not found", name)
+		}
+		p.resolve(name, nil)
+	}
+	// Types are written in name order, with the types they nest
+	var all []string
+	for name := range p.types {
+		all = append(all, name)
+	}
+	sort.Strings(all)
+	for _, name := range all {
+		if t := p.types[name]; t != nil {
+			types = append(types, t)
+		} else {
+			skipped = append(skipped, name+": "+p.reasons[name])
+		}
+	}
+	return p.name, types, skipped, nil
+}
+
+// hasFixedTags reports whether a struct has fields with a `fixed` tag.
+func (p *sourcePackage) hasFixedTags(name string) bool {
+	for _, field := range p.structs[name].Fields.List {
+		if _, _, _, ok := fixedtag.Parse(fieldTag(field)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the type to generate for a struct, or nil if it is not
+// supported. Structs being resolved are in stack, to detect cycles.
+func (p *sourcePackage) resolve(name string, stack []string) *genType {
+	if t, ok := p.types[name]; ok {
+		return t
+	}
+	for _, s := range stack {
+		if s == name {
+			p.reasons[name] = "recursive type"
+			return nil
+		}
+	}
+	t := &genType{Name: name, Length: p.lineLength(p.structs[name])}
+	if err := p.addFields(t, p.structs[name], append(stack, name)); err != nil {
+		p.types[name] = nil
+		p.reasons[name] = err.Error()
+		return nil
+	}
+	p.types[name] = t
+	return t
+}
+
+// addFields adds the tagged fields of a struct to t.
+func (p *sourcePackage) addFields(t *genType, st *ast.StructType, stack []string) error {
+	for _, field := range st.Fields.List {
+		tag := fieldTag(field)
+		names := fieldNames(field)
+		// Validation covers every field, tagged or not
+		if tag.Get("checkdigit") != "" || strings.Contains(tag.Get("validate"), "check=") {
+			return fmt.Errorf("field %s has check digits", names[0])
+		}
+		if tag.Get("validate") != "" {
+			t.Validate = true
+		}
+
+		b, e, format, ok := fixedtag.Parse(tag)
+		if !ok {
+			// Validation walks untagged structs and their lists too
+			if name := structName(field.Type); p.structs[name] != nil {
+				validates, err := p.validates(name, make(map[string]bool))
+				if err != nil {
+					return fmt.Errorf("field %s: %v", names[0], err)
+				}
+				t.Validate = t.Validate || validates
+			}
+			continue
+		}
+
+		for _, key := range []string{"tz", "fixedsplit", "listwidth"} {
+			if tag.Get(key) != "" {
+				return fmt.Errorf("field %s has a %s tag", names[0], key)
+			}
+		}
+		if b < 0 || b > e {
+			return fmt.Errorf("field %s has an invalid range", names[0])
+		}
+
+		f := genField{Begin: b, End: e, Format: format}
+		if err := p.fieldType(&f, field.Type, stack); err != nil {
+			return fmt.Errorf("field %s: %v", names[0], err)
+		}
+		if f.Kind == kindStruct && p.types[f.Type].Validate {
+			t.Validate = true
+		}
+		for _, name := range names {
+			f.Name = name
+			t.Fields = append(t.Fields, f)
+		}
+	}
+	return nil
+}
+
+// validates reports whether a struct or the structs it holds have
+// `validate` tags, which the reflection path checks whether the fields are
+// tagged or not. Structs already visited are in seen.
+func (p *sourcePackage) validates(name string, seen map[string]bool) (bool, error) {
+	if seen[name] {
+		return false, nil
+	}
+	seen[name] = true
+	var validates bool
+	for _, field := range p.structs[name].Fields.List {
+		tag := fieldTag(field)
+		if tag.Get("checkdigit") != "" || strings.Contains(tag.Get("validate"), "check=") {
+			return false, fmt.Errorf("type %s has check digits", name)
+		}
+		if tag.Get("validate") != "" {
+			validates = true
+		}
+		if sub := structName(field.Type); p.structs[sub] != nil {
+			subValidates, err := p.validates(sub, seen)
+			if err != nil {
+				return false, err
+			}
+			validates = validates || subValidates
+		}
+	}
+	return validates, nil
+}
+
+// lineLength returns the length of the records of a struct, as
+// gofixedlength.LineLength does: the highest end of its ranges and of those
+// of the structs it holds.
+func (p *sourcePackage) lineLength(st *ast.StructType) int {
+	var length int
+	for _, field := range st.Fields.List {
+		if _, e, _, ok := fixedtag.Parse(fieldTag(field)); ok && e > length {
+			length = e
+		}
+		if ident, ok := field.Type.(*ast.Ident); ok && p.structs[ident.Name] != nil {
+			if sub := p.lineLength(p.structs[ident.Name]); sub > length {
+				length = sub
+			}
+		}
+	}
+	return length
+}
+
+// fieldType sets the type and kind of a tagged field.
+func (p *sourcePackage) fieldType(f *genField, expr ast.Expr, stack []string) error {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		f.Ptr = true
+		expr = star.X
+	}
+	switch x := expr.(type) {
+	case *ast.SelectorExpr:
+		if pkg, ok := x.X.(*ast.Ident); ok && pkg.Name == "time" && x.Sel.Name == "Time" {
+			f.Type, f.Kind = "time.Time", kindTime
+			return nil
+		}
+	case *ast.Ident:
+		if p.structs[x.Name] != nil {
+			if p.resolve(x.Name, stack) == nil {
+				return fmt.Errorf("type %s is not supported", x.Name)
+			}
+			f.Type, f.Kind = x.Name, kindStruct
+			return nil
+		}
+		if f.Ptr {
+			break
+		}
+		f.Type = x.Name
+		switch x.Name {
+		case "string":
+			f.Kind = kindString
+			return nil
+		case "bool":
+			f.Kind = kindBool
+			return nil
+		case "int", "int8", "int16", "int32", "int64":
+			f.Kind = kindInt
+			f.Bits, _ = strconv.Atoi(strings.TrimPrefix(x.Name, "int"))
+			return nil
+		case "uint", "uint8", "uint16", "uint32", "uint64":
+			f.Kind = kindUint
+			f.Bits, _ = strconv.Atoi(strings.TrimPrefix(x.Name, "uint"))
+			return nil
+		case "float32", "float64":
+			f.Kind = kindFloat
+			f.Bits, _ = strconv.Atoi(strings.TrimPrefix(x.Name, "float"))
+			return nil
+		}
+	}
+	return fmt.Errorf("type is not supported")
+}
+
+// structName returns the name of the type of a field, or of the items of
+// a slice, pointers aside. Only names declared in the source are structs.
+func structName(expr ast.Expr) string {
+	for {
+		switch x := expr.(type) {
+		case *ast.StarExpr:
+			expr = x.X
+		case *ast.ArrayType:
+			expr = x.Elt
+		case *ast.Ident:
+			return x.Name
+		default:
+			return ""
+		}
+	}
+}
+
+func fieldTag(field *ast.Field) reflect.StructTag {
+	if field.Tag == nil {
+		return ""
+	}
+	tag, _ := strconv.Unquote(field.Tag.Value)
+	return reflect.StructTag(tag)
+}
+
+// fieldNames returns the names of a field, or the name of its type if
+// embedded.
+func fieldNames(field *ast.Field) []string {
+	if len(field.Names) == 0 {
+		switch x := field.Type.(type) {
+		case *ast.Ident:
+			return []string{x.Name}
+		case *ast.StarExpr:
+			if ident, ok := x.X.(*ast.Ident); ok {
+				return []string{ident.Name}
+			}
+		case *ast.SelectorExpr:
+			return []string{x.Sel.Name}
+		}
+		return []string{"?"}
+	}
+	var names []string
+	for _, name := range field.Names {
+		names = append(names, name.Name)
+	}
+	return names
+}
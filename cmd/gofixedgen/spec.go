@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/qrawl/gofixedlength"
+)
+
+// readJSONSpec reads a JSON schema, giving a type for every record type.
+func readJSONSpec(r io.Reader) ([]*genType, error) {
+	schema, err := gofixedlength.LoadSchema(r)
+	if err != nil {
+		return nil, err
+	}
+	var types []*genType
+	for _, record := range schema.Records {
+		t, err := typeFromLayout(goName(record.Name), record.Fields)
+		if err != nil {
+			return nil, err
+		}
+		t.Doc = fmt.Sprintf("%s is a %q record", t.Name, record.Name)
+		if record.Code != "" {
+			// Keep the code identifying the record type
+			for _, f := range t.Fields {
+				if f.Name == "RecordCode" {
+					return nil, fmt.Errorf("%s.%s: %v, reserved for the record code", t.Name, f.Name, gofixedlength.ErrDuplicateName)
+				}
+			}
+			code := genField{Name: "RecordCode", Type: "string", Kind: kindString, Begin: record.Begin, End: record.End}
+			code.Comment = fmt.Sprintf("Always %q", record.Code)
+			t.Fields = append([]genField{code}, t.Fields...)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// readCsvSpec reads the fields of a single record type, listed as
+// name,start,length,type[,format] lines. A first line whose start is not a
+// number is taken as a header.
+func readCsvSpec(r io.Reader, name string, base int) ([]*genType, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	lines, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var layout gofixedlength.Layout
+	for i, line := range lines {
+		if len(line) < 3 {
+			return nil, fmt.Errorf("line %d: expected name,start,length,type[,format]", i+1)
+		}
+		start, err := strconv.Atoi(line[1])
+		if err != nil && i == 0 {
+			continue // Header
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid start %q", i+1, line[1])
+		}
+		length, err := strconv.Atoi(line[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid length %q", i+1, line[2])
+		}
+		f := gofixedlength.LayoutField{Name: line[0], Begin: start - base, End: start - base + length}
+		if len(line) > 3 {
+			f.Type = gofixedlength.FieldType(strings.ToLower(line[3]))
+		}
+		if len(line) > 4 {
+			f.Format = line[4]
+		}
+		layout = append(layout, f)
+	}
+
+	schema := gofixedlength.Schema{Records: []gofixedlength.RecordLayout{{Name: name, Fields: layout}}}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	t, err := typeFromLayout(goName(name), layout)
+	if err != nil {
+		return nil, err
+	}
+	return []*genType{t}, nil
+}
+
+// typeFromLayout returns the type holding the fields of a layout.
+func typeFromLayout(name string, layout gofixedlength.Layout) (*genType, error) {
+	t := &genType{Name: name}
+	names := make(map[string]bool)
+	for _, f := range layout {
+		field := genField{Name: goName(f.Name), Begin: f.Begin, End: f.End, Format: f.Format, TZ: f.TZ}
+		switch f.Type {
+		case gofixedlength.TypeString, "":
+			field.Type, field.Kind = "string", kindString
+		case gofixedlength.TypeInt:
+			field.Type, field.Kind = "int", kindInt
+		case gofixedlength.TypeUint:
+			field.Type, field.Kind = "uint", kindUint
+		case gofixedlength.TypeFloat:
+			field.Type, field.Kind, field.Bits = "float64", kindFloat, 64
+		case gofixedlength.TypeBool:
+			field.Type, field.Kind = "bool", kindBool
+		case gofixedlength.TypeTime:
+			field.Type, field.Kind = "time.Time", kindTime
+		default:
+			return nil, fmt.Errorf("%s.%s: %v", name, f.Name, gofixedlength.ErrLayoutType)
+		}
+		if names[field.Name] {
+			return nil, fmt.Errorf("%s.%s: %v", name, field.Name, gofixedlength.ErrDuplicateName)
+		}
+		names[field.Name] = true
+		t.Fields = append(t.Fields, field)
+	}
+	return t, nil
+}
+
+// goName turns a field or record name into an exported Go identifier, as in
+// "account-number" to "AccountNumber".
+func goName(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			b.WriteString("F")
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "Field"
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package gofixedlength
+
+import (
+	"archive/zip"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrZipArchive         = errors.New("File is a zip archive, its entries must be read with ZipEntries")
+	ErrUnsupportedWriting = errors.New("Compression format is not supported for writing")
+)
+
+// Compression formats, found from the extension or the first bytes of a
+// file.
+const (
+	compressNone  = ""
+	compressGzip  = "gzip"
+	compressBzip2 = "bzip2"
+	compressZip   = "zip"
+)
+
+// compressMagic holds the first bytes of compressed files. As "BZh" can
+// start a text record, bzip2 files are told by the block size digit and
+// the magic of their first block, or of the end of an empty stream.
+var compressMagic = []struct {
+	format string
+	magic  []byte
+	digit  int // Index of a '1' to '9' digit, 0 if none
+}{
+	{compressGzip, []byte{0x1f, 0x8b, 0x08}, 0},
+	{compressBzip2, []byte("BZh01AY&SY"), 3},
+	{compressBzip2, []byte("BZh0\x17\x72\x45\x38\x50\x90"), 3},
+	{compressZip, []byte("PK\x03\x04"), 0},
+}
+
+// hasMagic reports whether head starts with magic, any digit from 1 to 9
+// being accepted at index digit.
+func hasMagic(head []byte, magic []byte, digit int) bool {
+	if len(head) < len(magic) {
+		return false
+	}
+	for i, c := range magic {
+		if i == digit && digit > 0 {
+			if head[i] < '1' || head[i] > '9' {
+				return false
+			}
+		} else if head[i] != c {
+			return false
+		}
+	}
+	return true
+}
+
+func compressionOf(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gz", ".gzip":
+		return compressGzip
+	case ".bz2", ".bzip2":
+		return compressBzip2
+	case ".zip":
+		return compressZip
+	}
+	return compressNone
+}
+
+// OpenFile opens a file for reading, decompressing gzip and bzip2 files
+// as they are read. Compressed files are recognized by their extension or
+// their first bytes. Zip archives give ErrZipArchive.
+func OpenFile(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(f, compressionOf(filename))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &multiCloser{Reader: r, closers: []io.Closer{f}}, nil
+}
+
+// NewDecompressReader returns a reader decompressing r if it holds gzip or
+// bzip2 data, recognized by its first bytes, or reading it as it is.
+func NewDecompressReader(r io.Reader) (io.Reader, error) {
+	return decompress(r, compressNone)
+}
+
+// decompress returns a reader decompressing r, in format if given or
+// else in the one its first bytes tell.
+func decompress(r io.Reader, format string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if format == compressNone {
+		head, _ := br.Peek(10)
+		for _, m := range compressMagic {
+			if hasMagic(head, m.magic, m.digit) {
+				format = m.format
+				break
+			}
+		}
+	}
+	switch format {
+	case compressGzip:
+		return gzip.NewReader(br)
+	case compressBzip2:
+		return bzip2.NewReader(br), nil
+	case compressZip:
+		return nil, ErrZipArchive
+	}
+	return br, nil
+}
+
+// CreateFile creates a file for writing, compressed with gzip if its
+// extension is .gz.
+func CreateFile(filename string) (io.WriteCloser, error) {
+	format := compressionOf(filename)
+	if format != compressNone && format != compressGzip {
+		return nil, ErrUnsupportedWriting
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	if format == compressNone {
+		return f, nil
+	}
+	zw := gzip.NewWriter(f)
+	return &multiCloser{Writer: zw, closers: []io.Closer{zw, f}}, nil
+}
+
+// ZipEntries calls fn with the name and content of each file in a zip
+// archive, in archive order, decompressing entries in gzip or bzip2 as
+// OpenFile does. It stops at the first error fn returns.
+func ZipEntries(filename string, fn func(name string, r io.Reader) error) error {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		if err := zipEntry(entry, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func zipEntry(entry *zip.File, fn func(name string, r io.Reader) error) error {
+	rc, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	r, err := decompress(rc, compressionOf(entry.Name))
+	if err != nil {
+		return err
+	}
+	return fn(entry.Name, r)
+}
+
+// multiCloser closes its closers in order, returning the first error.
+type multiCloser struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var first error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
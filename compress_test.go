@@ -0,0 +1,130 @@
+package gofixedlength
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bzip2Test holds "123\nABC\n" compressed with bzip2.
+const bzip2Test = "\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\xcb\x41\xb3\x58\x00\x00\x01\x4c\x00\x00\x10\x38\x00\x38\x00\x20\x00\x22\x18\x02\x18\x0a\xca\x66\x5c\x2e\xe4\x8a\x70\xa1\x21\x96\x83\x66\xb0"
+
+func gzipped(t *testing.T, s string) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestCompressedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w, err := CreateFile(filepath.Join(dir, "test.txt.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "123\nABC\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "gzip.dat"), gzipped(t, "123\r\nABC\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "test.bz2"), []byte(bzip2Test), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"test.txt.gz", "gzip.dat", "test.bz2"} {
+		s, err := RecordsFromFile(filepath.Join(dir, name), EOL_AUTO)
+		if err != nil || strings.Join(s, "|") != "123|ABC|" {
+			t.Errorf("%s: got %q (%v)", name, s, err)
+		}
+	}
+
+	// Plain records looking like compressed data
+	for _, data := range []string{"BZh BANK  0001\n", "BZh91AY&S\n", "PK\x03\n"} {
+		name := filepath.Join(dir, "plain.txt")
+		if err := ioutil.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := RecordsFromFile(name, EOL_UNIX)
+		if err != nil || len(s) != 2 || s[0]+"\n" != data {
+			t.Errorf("%q: got %q (%v)", data, s, err)
+		}
+		s, err = RecordsFromFileContext(context.Background(), name, EOL_UNIX)
+		if err != nil || len(s) != 2 || s[0]+"\n" != data {
+			t.Errorf("%q: got %q (%v) with a context", data, s, err)
+		}
+	}
+
+	if _, err := CreateFile(filepath.Join(dir, "test.bz2")); err != ErrUnsupportedWriting {
+		t.Errorf("Expected ErrUnsupportedWriting, got %v", err)
+	}
+}
+
+func TestZipEntries(t *testing.T) {
+	file, err := ioutil.TempFile("", "compress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	zw := zip.NewWriter(file)
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"a.txt", []byte("A000001\n")},
+		{"dir/", nil},
+		{"dir/b.txt.gz", gzipped(t, "A000002\n")},
+	} {
+		w, err := zw.Create(entry.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(entry.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if _, err := OpenFile(file.Name()); err != ErrZipArchive {
+		t.Errorf("Expected ErrZipArchive, got %v", err)
+	}
+
+	var names []string
+	err = ZipEntries(file.Name(), func(name string, r io.Reader) error {
+		var v parallelTest
+		if err := NewFixedDecoder(r).Decode(&v); err != nil {
+			return err
+		}
+		if v.Index != len(names)+1 {
+			t.Errorf("%s: got %+v", name, v)
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil || strings.Join(names, "|") != "a.txt|dir/b.txt.gz" {
+		t.Errorf("Got entries %q (%v)", names, err)
+	}
+}
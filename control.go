@@ -0,0 +1,414 @@
+package gofixedlength
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrControlMismatch = errors.New("Control total does not match the records")
+	ErrControlSource   = errors.New("Control total source field is not numeric")
+	ErrControlTag      = errors.New("Invalid control tag")
+)
+
+// ControlError describes a control field whose value does not match the
+// total computed over the records of its group.
+type ControlError struct {
+	Field    string // Name of the control field
+	Control  string // Content of the `control` tag
+	Expected string // Value computed from the records
+	Found    string // Value found in the control field
+}
+
+func (e *ControlError) Error() string {
+	return fmt.Sprintf("%s (%s): %s, found %s, computed %s", ErrControlMismatch.Error(), e.Control, e.Field, e.Found, e.Expected)
+}
+
+// Unwrap returns ErrControlMismatch.
+func (e *ControlError) Unwrap() error {
+	return ErrControlMismatch
+}
+
+// controlSpec is the parsed content of a `control` tag. Control fields
+// belong to a record (usually a trailer) and are computed over the other
+// records and groups of the group containing it:
+//
+//	type BatchTrailer struct {
+//		Count  int     `fixed:"1-7" control:"count=Detail"`  // Detail records
+//		Total  float64 `fixed:"7-19,2" control:"sum=Amount"` // Sum of Amount fields
+//		Hash   int     `fixed:"19-29" control:"hash=RDFI"`   // Sum of RDFI fields mod 10^10
+//	}
+//
+// "count" alone counts every record of the group, the control record
+// included; "count=T" counts the records or groups of type T. "sum=F" adds
+// up the F fields of every record having one, control records aside, while
+// "hash=F" does the same but only keeps the last digits: as many as the
+// field is wide, or as many as given after a comma ("hash=F,8").
+type controlSpec struct {
+	index  int // Index of the control field in the record
+	tag    string
+	op     string // count, sum or hash
+	source string // Type name for count, field name for sum and hash
+	digits int
+	format string // Format of the control field's `fixed` tag
+}
+
+func controlSpecs(t reflect.Type) ([]controlSpec, error) {
+	var specs []controlSpec
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+		tag := typeField.Tag.Get("control")
+		if tag == "" {
+			continue
+		}
+		spec := controlSpec{index: i, tag: tag, op: tag}
+		if j := strings.Index(tag, "="); j >= 0 {
+			spec.op, spec.source = tag[:j], tag[j+1:]
+		}
+
+		b, e, cFormat, ok := fixedTag(typeField.Tag)
+		spec.format = cFormat
+		if ok {
+			spec.digits = e - b
+		}
+
+		switch spec.op {
+		case "count":
+		case "sum":
+			if spec.source == "" {
+				return nil, ErrControlTag
+			}
+		case "hash":
+			if j := strings.Index(spec.source, ","); j >= 0 {
+				digits, err := strconv.Atoi(spec.source[j+1:])
+				if err != nil {
+					return nil, ErrControlTag
+				}
+				spec.source, spec.digits = spec.source[:j], digits
+			}
+			if spec.source == "" || spec.digits <= 0 {
+				return nil, ErrControlTag
+			}
+		default:
+			return nil, ErrControlTag
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// controlTotal accumulates a control total both as an integer and as a
+// floating-point number, the control field picking the one it needs.
+type controlTotal struct {
+	i int64
+	f float64
+}
+
+func (c *controlTotal) add(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.i += v.Int()
+		c.f += float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.i += int64(v.Uint())
+		c.f += float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		c.i += int64(v.Float())
+		c.f += v.Float()
+	case reflect.String:
+		s := strings.TrimSpace(v.String())
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return ErrControlSource
+		}
+		c.i += n
+		c.f += float64(n)
+	default:
+		return ErrControlSource
+	}
+	return nil
+}
+
+// start returns the initial total of the control field.
+func (spec controlSpec) start() controlTotal {
+	if spec.op == "count" && spec.source == "" {
+		return controlTotal{i: 1, f: 1} // The control record itself
+	}
+	return controlTotal{}
+}
+
+// add adds a record, or a group if isRecord is false, to total.
+func (spec controlSpec) add(total *controlTotal, elem reflect.Value, isRecord bool) error {
+	if spec.op == "count" {
+		if spec.source == "" && isRecord || spec.source != "" && elem.Type().Name() == spec.source {
+			total.i++
+			total.f++
+		}
+		return nil
+	}
+	if !isRecord || isControlRecord(elem.Type()) {
+		return nil // Nested totals are not added up again
+	}
+	field := elem.FieldByName(spec.source)
+	if !field.IsValid() {
+		return nil
+	}
+	if k := reflect.Indirect(field).Kind(); spec.op == "hash" && (k == reflect.Float32 || k == reflect.Float64) {
+		// Hash totals add up integers, like routing numbers
+		return ErrControlSource
+	}
+	return total.add(field)
+}
+
+// isControlRecord reports whether records of type t hold control fields.
+func isControlRecord(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("control") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// finish returns the value of the control field for total.
+func (spec controlSpec) finish(total controlTotal) controlTotal {
+	if spec.op == "hash" && spec.digits < 19 {
+		total.i %= int64(pow(10, spec.digits))
+		total.f = float64(total.i)
+	}
+	return total
+}
+
+// computeControls computes the totals described by specs over the elements
+// of group, skipping its field named self which holds the control record.
+func computeControls(group reflect.Value, self string, specs []controlSpec) ([]controlTotal, error) {
+	totals := make([]controlTotal, len(specs))
+	for i, spec := range specs {
+		totals[i] = spec.start()
+	}
+	err := walkElements(group, self, func(elem reflect.Value, isRecord bool) error {
+		for i, spec := range specs {
+			if err := spec.add(&totals[i], elem, isRecord); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	for i, spec := range specs {
+		totals[i] = spec.finish(totals[i])
+	}
+	return totals, err
+}
+
+// formatControl formats a control total the way the control field would
+// hold it, so that totals and fields can be compared.
+func formatControl(total controlTotal, field reflect.Value, spec controlSpec) string {
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		decimals, err := strconv.Atoi(spec.format)
+		if err != nil {
+			decimals = 2
+		}
+		return strconv.FormatFloat(total.f, 'f', decimals, 64)
+	default:
+		return strconv.FormatInt(total.i, 10)
+	}
+}
+
+// checkControls verifies the control fields of rec, a record held by the
+// field named self of group.
+func checkControls(group reflect.Value, self string, rec reflect.Value) error {
+	specs, err := controlSpecs(rec.Type())
+	if err != nil || len(specs) == 0 {
+		return err
+	}
+	totals, err := computeControls(group, self, specs)
+	if err != nil {
+		return err
+	}
+	return compareControls(rec, specs, totals)
+}
+
+// compareControls verifies the control fields of rec against totals.
+func compareControls(rec reflect.Value, specs []controlSpec, totals []controlTotal) error {
+	for i, spec := range specs {
+		field := rec.Field(spec.index)
+		var found controlTotal
+		if err := found.add(field); err != nil {
+			return err
+		}
+		expected := formatControl(totals[i], field, spec)
+		if actual := formatControl(found, field, spec); actual != expected {
+			return &ControlError{
+				Field:    rec.Type().Field(spec.index).Name,
+				Control:  spec.tag,
+				Expected: expected,
+				Found:    actual,
+			}
+		}
+	}
+	return nil
+}
+
+// applyControls sets the control fields of rec, a record held by the field
+// named self of group, to the computed totals.
+func applyControls(group reflect.Value, self string, rec reflect.Value) error {
+	specs, err := controlSpecs(rec.Type())
+	if err != nil || len(specs) == 0 {
+		return err
+	}
+	totals, err := computeControls(group, self, specs)
+	if err != nil {
+		return err
+	}
+	return setControls(rec, specs, totals)
+}
+
+// setControls sets the control fields of rec to totals.
+func setControls(rec reflect.Value, specs []controlSpec, totals []controlTotal) error {
+	for i, spec := range specs {
+		total := totals[i]
+		field := rec.Field(spec.index)
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(total.i)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetUint(uint64(total.i))
+		case reflect.Float32, reflect.Float64:
+			field.SetFloat(total.f)
+		case reflect.String:
+			field.SetString(fmt.Sprintf("%0*d", spec.digits, total.i))
+		default:
+			return ErrControlSource
+		}
+	}
+	return nil
+}
+
+// ControlAccumulator computes the control totals of a control record over a
+// stream of records, for readers and writers which do not hold the whole
+// file structure, like FixedDecoder and FixedEncoder (see SetControls):
+//
+//	acc, err := NewControlAccumulator(BatchTrailer{})
+//	for _, detail := range details {
+//		acc.Add(detail)
+//	}
+//	err = acc.Check(trailer) // When decoding
+//	err = acc.Apply(&trailer) // When encoding, before Marshal
+//
+// Records are counted by their type name, as groups do not exist in a
+// stream.
+type ControlAccumulator struct {
+	control reflect.Type
+	specs   []controlSpec
+	totals  []controlTotal
+}
+
+// NewControlAccumulator returns an accumulator for the `control` tags of
+// the type of control, a struct or a pointer to one.
+func NewControlAccumulator(control interface{}) (*ControlAccumulator, error) {
+	t := reflect.TypeOf(control)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrInvalidTarget
+	}
+	specs, err := controlSpecs(t)
+	if err != nil {
+		return nil, err
+	}
+	a := &ControlAccumulator{control: t, specs: specs}
+	a.Reset()
+	return a, nil
+}
+
+// Reset starts a new set of totals, as after a control record.
+func (a *ControlAccumulator) Reset() {
+	a.totals = make([]controlTotal, len(a.specs))
+	for i, spec := range a.specs {
+		a.totals[i] = spec.start()
+	}
+}
+
+// IsControl reports whether v is a control record of the accumulator.
+func (a *ControlAccumulator) IsControl(v interface{}) bool {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	return val.IsValid() && val.Type() == a.control
+}
+
+// Add adds a record to the totals.
+func (a *ControlAccumulator) Add(v interface{}) error {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	for i, spec := range a.specs {
+		if err := spec.add(&a.totals[i], val, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check verifies the control fields of a control record against the
+// totals, returning a *ControlError on mismatch.
+func (a *ControlAccumulator) Check(control interface{}) error {
+	val := reflect.Indirect(reflect.ValueOf(control))
+	if !val.IsValid() || val.Type() != a.control {
+		return ErrInvalidTarget
+	}
+	return compareControls(val, a.specs, a.finished())
+}
+
+// Apply sets the control fields of a control record, which must be a
+// pointer, to the totals.
+func (a *ControlAccumulator) Apply(control interface{}) error {
+	val := reflect.ValueOf(control)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Type() != a.control {
+		return ErrInvalidTarget
+	}
+	return setControls(val.Elem(), a.specs, a.finished())
+}
+
+func (a *ControlAccumulator) finished() []controlTotal {
+	totals := make([]controlTotal, len(a.specs))
+	for i, spec := range a.specs {
+		totals[i] = spec.finish(a.totals[i])
+	}
+	return totals
+}
+
+// decoded adds a decoded record to the totals or, for a control record,
+// checks it and starts anew.
+func (a *ControlAccumulator) decoded(v interface{}) error {
+	if !a.IsControl(v) {
+		return a.Add(v)
+	}
+	defer a.Reset()
+	return a.Check(v)
+}
+
+// applied returns a copy of a control record having its control fields set
+// to the totals.
+func (a *ControlAccumulator) applied(v interface{}) (interface{}, error) {
+	out := reflect.New(a.control)
+	out.Elem().Set(reflect.Indirect(reflect.ValueOf(v)))
+	return out.Interface(), a.Apply(out.Interface())
+}
+
+// encoded adds an encoded record to the totals or, after a control record,
+// starts anew.
+func (a *ControlAccumulator) encoded(v interface{}) error {
+	if a.IsControl(v) {
+		a.Reset()
+		return nil
+	}
+	return a.Add(v)
+}
@@ -0,0 +1,225 @@
+package gofixedlength
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type controlFile struct {
+	Batches []controlBatch
+	Trailer controlFileTrailer `record:"0-1,9"`
+}
+
+type controlBatch struct {
+	Header  controlBatchHeader  `record:"0-1,5"`
+	Details []controlDetail     `record:"0-1,6"`
+	Trailer controlBatchTrailer `record:"0-1,8"`
+}
+
+type controlBatchHeader struct {
+	Type string `fixed:"0-1"`
+}
+
+type controlDetail struct {
+	Type    string  `fixed:"0-1"`
+	Routing string  `fixed:"1-5"`
+	Amount  float64 `fixed:"5-11,2"`
+}
+
+type controlBatchTrailer struct {
+	Type   string  `fixed:"0-1"`
+	Count  int     `fixed:"1-3" control:"count=controlDetail"`
+	Hash   int     `fixed:"3-7" control:"hash=Routing"`
+	Amount float64 `fixed:"7-14,2" control:"sum=Amount"`
+}
+
+type controlFileTrailer struct {
+	Type    string `fixed:"0-1"`
+	Batches int    `fixed:"1-3" control:"count=controlBatch"`
+	Records string `fixed:"3-7" control:"count"`
+}
+
+var controlTestRecords = []string{
+	"5",
+	"66000001.50",
+	"67000002.25",
+	"80230000003.75",
+	"5",
+	"80000000000.00",
+	"9020007",
+}
+
+func TestControlTotals(t *testing.T) {
+	var out controlFile
+	if err := UnmarshalRecords(controlTestRecords, &out); err != nil {
+		t.Fatalf("UnmarshalRecords failed: %v", err)
+	}
+	if out.Batches[0].Trailer.Hash != 3000 {
+		t.Errorf("Hash parsed as %d", out.Batches[0].Trailer.Hash)
+	}
+}
+
+func TestControlTotalsMismatch(t *testing.T) {
+	records := append([]string(nil), controlTestRecords...)
+	records[3] = "80230000003.70"
+	var out controlFile
+	err := UnmarshalRecords(records, &out)
+	if !errors.Is(err, ErrControlMismatch) {
+		t.Fatalf("Expected a control total mismatch, got %v", err)
+	}
+	if lineErr, ok := err.(*LineError); !ok || lineErr.Line != 4 {
+		t.Errorf("Mismatch reported as %v", err)
+	}
+
+	records[3] = controlTestRecords[3]
+	records[6] = "9030007"
+	if err := UnmarshalRecords(records, &out); !errors.Is(err, ErrControlMismatch) {
+		t.Errorf("Expected a batch count mismatch, got %v", err)
+	}
+}
+
+func TestControlTotalsMarshal(t *testing.T) {
+	in := controlFile{
+		Batches: []controlBatch{{
+			Header: controlBatchHeader{"5"},
+			Details: []controlDetail{
+				{"6", "6000", 1.5},
+				{"6", "7000", 2.25},
+			},
+			Trailer: controlBatchTrailer{Type: "8"},
+		}, {
+			Header:  controlBatchHeader{"5"},
+			Trailer: controlBatchTrailer{Type: "8"},
+		}},
+		Trailer: controlFileTrailer{Type: "9"},
+	}
+	out, err := MarshalRecords(in)
+	if err != nil {
+		t.Fatalf("MarshalRecords failed: %v", err)
+	}
+	if len(out) != len(controlTestRecords) {
+		t.Fatalf("Marshalled %d records", len(out))
+	}
+	for i := range out {
+		if out[i] != controlTestRecords[i] {
+			t.Errorf("Record %d marshalled as '%s'", i, out[i])
+		}
+	}
+	if in.Batches[0].Trailer.Count != 0 {
+		t.Errorf("MarshalRecords modified its input")
+	}
+}
+
+func TestControlAccumulatorStream(t *testing.T) {
+	acc, err := NewControlAccumulator(controlBatchTrailer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	e := NewFixedEncoder(&b, EOL_UNIX)
+	e.SetControls(acc)
+	for _, v := range []interface{}{
+		controlDetail{"6", "6000", 1.5},
+		&controlDetail{"6", "7000", 2.25},
+		controlBatchTrailer{Type: "8", Count: 42},
+		controlBatchTrailer{Type: "8"},
+	} {
+		if err := e.Encode(v); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	e.Flush()
+	expected := controlTestRecords[1] + "\n" + controlTestRecords[2] + "\n" + controlTestRecords[3] + "\n" + controlTestRecords[5] + "\n"
+	if b.String() != expected {
+		t.Errorf("Encoded as %q, expected %q", b.String(), expected)
+	}
+
+	// Decoding checks the totals
+	data := strings.Replace(b.String(), "80230000003.75", "80230000003.70", 1)
+	d := NewFixedDecoder(strings.NewReader(data))
+	acc.Reset()
+	d.SetControls(acc)
+	var detail controlDetail
+	var trailer controlBatchTrailer
+	for _, v := range []interface{}{&detail, &detail, &trailer} {
+		err = d.Decode(v)
+	}
+	var controlErr *ControlError
+	if !errors.As(err, &controlErr) || controlErr.Field != "Amount" || controlErr.Expected != "3.75" {
+		t.Errorf("Expected an Amount mismatch, got %v", err)
+	}
+	if err := d.Decode(&trailer); err != nil {
+		t.Errorf("Empty batch rejected: %v", err)
+	}
+}
+
+type controlFloatHash struct {
+	Hash int `fixed:"0-4" control:"hash=Amount"`
+}
+
+func TestControlHashSource(t *testing.T) {
+	acc, err := NewControlAccumulator(&controlFloatHash{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := acc.Add(controlDetail{"6", "6000", 1.5}); err != ErrControlSource {
+		t.Errorf("Expected ErrControlSource for a float hash, got %v", err)
+	}
+	if _, err := NewControlAccumulator(42); err != ErrInvalidTarget {
+		t.Errorf("Expected ErrInvalidTarget, got %v", err)
+	}
+}
+
+type controlSumFile struct {
+	Batches []controlSumBatch
+	Trailer controlSumTrailer `record:"0-1,9"`
+}
+
+type controlSumBatch struct {
+	Details []controlSumDetail `record:"0-1,6"`
+	Trailer *controlSumTrailer `record:"0-1,8"`
+}
+
+type controlSumDetail struct {
+	Type   string  `fixed:"0-1"`
+	Amount float64 `fixed:"1-7,2"`
+}
+
+type controlSumTrailer struct {
+	Type   string  `fixed:"0-1"`
+	Amount float64 `fixed:"1-7,2" control:"sum=Amount"`
+}
+
+func TestControlTotalsNested(t *testing.T) {
+	in := controlSumFile{
+		Batches: []controlSumBatch{{
+			Details: []controlSumDetail{{"6", 1.5}, {"6", 2}},
+			Trailer: &controlSumTrailer{Type: "8"},
+		}, {
+			Details: []controlSumDetail{{"6", 0.25}},
+			Trailer: &controlSumTrailer{Type: "8"},
+		}},
+		Trailer: controlSumTrailer{Type: "9"},
+	}
+	records, err := MarshalRecords(&in)
+	if err != nil {
+		t.Fatalf("MarshalRecords failed: %v", err)
+	}
+	expected := []string{"6001.50", "6002.00", "8003.50", "6000.25", "8000.25", "9003.75"}
+	if strings.Join(records, "|") != strings.Join(expected, "|") {
+		t.Errorf("Marshalled as %q", records)
+	}
+	if in.Batches[0].Trailer.Amount != 0 {
+		t.Errorf("MarshalRecords modified its input")
+	}
+
+	var out controlSumFile
+	if err := UnmarshalRecords(records, &out); err != nil {
+		t.Fatalf("UnmarshalRecords failed: %v", err)
+	}
+	if out.Trailer.Amount != 3.75 || out.Batches[0].Trailer.Amount != 3.5 {
+		t.Errorf("Totals parsed as %+v", out)
+	}
+}
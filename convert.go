@@ -0,0 +1,282 @@
+package gofixedlength
+
+import (
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// This file holds the conversion between field values and text shared by
+// the fixed-length and the delimited codecs, so that every type and option
+// behaves the same way in both formats.
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// isTimeType reports whether t is time.Time or *time.Time.
+func isTimeType(t reflect.Type) bool {
+	return t == timeType || t.Kind() == reflect.Ptr && t.Elem() == timeType
+}
+
+// fieldOptions holds the options of a field which drive its conversion.
+type fieldOptions struct {
+	format   string         // Time layout, or number of decimals for floats
+	location *time.Location // Location for times without zone information
+	check    string         // Check digit algorithm of a `checkdigit` tag
+}
+
+// newFieldOptions reads the options of a field, given the format found in
+// its `fixed` or `csv` tag and the default location. Times are parsed in
+// UTC and printed unchanged when there is no location.
+func newFieldOptions(typeField reflect.StructField, format string, location *time.Location) (fieldOptions, error) {
+	opts := fieldOptions{format: format, location: location, check: typeField.Tag.Get("checkdigit")}
+	if tz := typeField.Tag.Get("tz"); tz != "" {
+		loc, err := loadLocation(tz)
+		if err != nil {
+			return opts, err
+		}
+		opts.location = loc
+	}
+	return opts, nil
+}
+
+var locationCache sync.Map // Locations loaded for `tz` tags
+
+func loadLocation(tz string) (*time.Location, error) {
+	if loc, ok := locationCache.Load(tz); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, err
+	}
+	locationCache.Store(tz, loc)
+	return loc, nil
+}
+
+// decodeValue converts s and stores it into field. It returns false for
+// kinds it does not handle, like embedded structs. Values which cannot be
+// parsed return an error and leave the field untouched. Spaces around
+// times are ignored.
+func decodeValue(field reflect.Value, s string, opts fieldOptions) (bool, error) {
+	if isTimeType(field.Type()) {
+		if strings.TrimSpace(s) == "" {
+			field.Set(reflect.Zero(field.Type()))
+			return true, nil
+		}
+		timeObject, err := ParseTime(s, opts.format, opts.location)
+		if err != nil {
+			return true, err
+		}
+		if field.Kind() == reflect.Ptr {
+			field.Set(reflect.ValueOf(&timeObject))
+		} else {
+			field.Set(reflect.ValueOf(timeObject))
+		}
+		return true, nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		v, err := ParseBool(s)
+		if err != nil {
+			return true, err
+		}
+		field.SetBool(v)
+	case reflect.Float32, reflect.Float64:
+		v, err := ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		field.SetFloat(v)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := ParseInt(s, field.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		field.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := ParseUint(s, field.Type().Bits())
+		if err != nil {
+			return true, err
+		}
+		field.SetUint(v)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// encodeValue formats the value of field. It returns false for kinds it
+// does not handle, like embedded structs. Floating-point values are printed
+// with the number of decimals in the format, or with the shortest
+// representation if there is none.
+func encodeValue(field reflect.Value, opts fieldOptions) (string, bool) {
+	if isTimeType(field.Type()) {
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			return "", true
+		}
+		return formatTime(reflect.Indirect(field).Interface().(time.Time), opts.format, opts.location), true
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), true
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(field.Float(), field.Type().Bits(), opts.format), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), true
+	}
+	return "", false
+}
+
+func formatFloat(v float64, bits int, format string) string {
+	decimals, err := strconv.Atoi(format)
+	if err != nil {
+		decimals = -1
+	}
+	s := strconv.FormatFloat(v, 'f', decimals, bits)
+	if DECIMAL_COMMA {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+func formatTime(t time.Time, layout string, loc *time.Location) string {
+	if loc != nil {
+		t = t.In(loc)
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return t.Format(layout)
+}
+
+// The following functions convert single values with the same rules as
+// Unmarshal and Marshal, without reflection. They are used by the code
+// generated by gofixedgen.
+
+// ParseInt parses a signed integer of the given bit size.
+func ParseInt(s string, bits int) (int64, error) {
+	return strconv.ParseInt(s, 10, bits)
+}
+
+// ParseUint parses an unsigned integer of the given bit size.
+func ParseUint(s string, bits int) (uint64, error) {
+	return strconv.ParseUint(s, 10, bits)
+}
+
+// ParseFloat parses a floating-point number of the given bit size,
+// honouring DECIMAL_COMMA.
+func ParseFloat(s string, bits int) (float64, error) {
+	if DECIMAL_COMMA {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	return strconv.ParseFloat(s, bits)
+}
+
+// ParseBool parses a boolean.
+func ParseBool(s string) (bool, error) {
+	return strconv.ParseBool(s)
+}
+
+// ParseTime parses a time with layout, time.RFC3339 if empty, in loc (UTC
+// if nil) when there is no zone information. Blank text gives the zero
+// time.
+func ParseTime(s, layout string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+// FormatInt formats a signed integer for a fixed field of the given width,
+// filled with zeroes after the sign.
+func FormatInt(v int64, width int) string {
+	return padRight(padNumber(strconv.FormatInt(v, 10), width), width)
+}
+
+// FormatUint formats an unsigned integer for a fixed field of the given
+// width, filled with zeroes.
+func FormatUint(v uint64, width int) string {
+	return padRight(padNumber(strconv.FormatUint(v, 10), width), width)
+}
+
+// FormatFloat formats a floating-point number of the given bit size for a
+// fixed field, with the number of decimals in format (two if empty),
+// filled with zeroes after the sign.
+func FormatFloat(v float64, bits int, format string, width int) string {
+	if format == "" {
+		format = "2"
+	}
+	decimals, err := strconv.Atoi(format)
+	if err != nil {
+		log.Println("Found non-valid format for float:", format)
+		format = "0"
+	}
+	integerPartLength := width - 1 - decimals
+	integerPart := int(v)
+	if integerPart >= pow(integerPartLength, 10) {
+		log.Printf("This float number (%v) seems to be too big for output length (%v).\n", integerPart, integerPartLength)
+	}
+	return padRight(padNumber(formatFloat(v, bits, format), width), width)
+}
+
+// FormatBool formats a boolean for a fixed field, as "1" or "0" when
+// "true" or "false" do not fit.
+func FormatBool(v bool, width int) string {
+	s := strconv.FormatBool(v)
+	if utf8.RuneCountInString(s) > width {
+		s = "0"
+		if v {
+			s = "1"
+		}
+	}
+	return padRight(s, width)
+}
+
+// FormatString cuts text to the width of a fixed field, or fills it with
+// spaces.
+func FormatString(s string, width int) string {
+	return padRight(truncate(s, width), width)
+}
+
+// FormatTime formats a time for a fixed field with layout, time.RFC3339 if
+// empty, converted to loc if not nil.
+func FormatTime(t time.Time, layout string, loc *time.Location, width int) string {
+	if len(layout) != width {
+		log.Println("cFormat for this time.Time object doesn't match the field length") // Maybe this kind of parsing error check should be done elsewhere
+	}
+	return padRight(formatTime(t, layout, loc), width)
+}
+
+// isIntegerKind reports whether k is a signed or unsigned integer kind.
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isNumberKind reports whether k is an integer or floating-point kind.
+func isNumberKind(k reflect.Kind) bool {
+	return isIntegerKind(k) || k == reflect.Float32 || k == reflect.Float64
+}
@@ -1,11 +1,72 @@
 package gofixedlength
 
 import (
+	"errors"
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
+var (
+	ErrCsvQuote      = errors.New("Invalid quoting in delimited record")
+	ErrMissingColumn = errors.New("Required column is missing from the header")
+	ErrUnknownColumn = errors.New("Column is not mapped to any field")
+)
+
+// CsvOptions configures the parsing of delimited records.
+type CsvOptions struct {
+	Separator        string // Field separator, "," if empty
+	Quote            rune   // Quote character, '"' if zero
+	TrimLeadingSpace bool   // Ignore white space before a field
+
+	// Header holds the column names used to resolve `csv:"name=..."` tags,
+	// usually read with ParseCsvHeader.
+	Header []string
+	// DisallowUnknownColumns rejects header columns not mapped to a field.
+	DisallowUnknownColumns bool
+	// Location is used for times without zone information, UTC if nil.
+	// A `tz:"Europe/Rome"` tag overrides it for a single field.
+	Location *time.Location
+}
+
+// CsvStreamOptions configures CsvDecoder and CsvEncoder: records are parsed
+// with the embedded CsvOptions.
+type CsvStreamOptions struct {
+	CsvOptions
+	HasHeader bool   // The first record is a header
+	Comment   rune   // Lines starting with this character are skipped
+	EOL       string // Record terminator to write, EOL_UNIX if empty
+}
+
+// ColumnError reports a header column which does not fit the struct.
+type ColumnError struct {
+	Column string
+	Err    error
+}
+
+func (e *ColumnError) Error() string {
+	return e.Err.Error() + ": " + e.Column
+}
+
+// Unwrap returns the underlying error.
+func (e *ColumnError) Unwrap() error {
+	return e.Err
+}
+
+// ParseCsvHeader splits a header line into column names, trimming spaces
+// around them.
+func ParseCsvHeader(line string, opts CsvOptions) ([]string, error) {
+	names, err := splitCsv(line, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+	return names, nil
+}
+
 // UnmarshalCsv unmarshals string data into an annotated interface. This
 // should resemble:
 //
@@ -13,6 +74,7 @@ import (
 // 		ValA      string        `csv:"0"`
 //		ValB      int           `csv:"1"`
 //		ValC      *EmbeddedType `csv:"2" csvsplit:"~"`
+//		ValD      time.Time     `csv:"3,2006-01-02"` // Standard Go time formatting
 //		WholeLine string        `csv:"raw"`
 // 	}
 //	type EmbeddedType struct {
@@ -21,29 +83,65 @@ import (
 //	}
 //
 //	var out SomeType
-//	err := Unmarshal("A,2,X~Y", "," &out)
+//	err := UnmarshalCsv("A,2,X~Y,2015-01-14", ",", &out)
 //
 // String offsets are zero based.
+// time.Time and *time.Time fields are parsed with the layout following the
+// column (time.RFC3339 if missing), in the location given by their `tz` tag
+// or by the options; an empty value leaves a *time.Time nil.
+// Slices hold lists, split by their `csvsplit` separator, as in
+// `csv:"4" csvsplit:";"`, or into items of their `listwidth`. Struct items
+// are parsed with Unmarshal, or with UnmarshalCsv using their `itemsplit`
+// separator.
+// Columns can also be mapped by name, as in `csv:"name=AccountID"`, when
+// the header is given to UnmarshalCsvOptions. A named column missing from
+// the header is an error if the field is `validate:"required"`, and leaves
+// the field untouched otherwise.
+// Fields are parsed as in RFC 4180: they can be enclosed in double quotes
+// to contain separators, line breaks and doubled quotes.
+// Decoded values are checked against their `validate` tags (see Validate).
 func UnmarshalCsv(data string, sep string, v interface{}) error {
+	return UnmarshalCsvOptions(data, CsvOptions{Separator: sep}, v)
+}
+
+// UnmarshalCsvOptions works like UnmarshalCsv, with the parsing configured
+// by opts. Embedded objects are parsed with the same options, except for
+// their `csvsplit` separator and header.
+func UnmarshalCsvOptions(data string, opts CsvOptions, v interface{}) error {
 	//debugStruct(v)
-	var val reflect.Value
+	val := csvTarget(v)
+	columns, err := resolveCsvColumns(val.Type(), opts)
+	if err != nil {
+		return err
+	}
+	return unmarshalCsvColumns(data, opts, columns, val)
+}
+
+// csvTarget returns the struct value v points to.
+func csvTarget(v interface{}) reflect.Value {
 	if reflect.TypeOf(v).Name() != "" {
-		val = reflect.ValueOf(v)
-	} else {
-		val = reflect.ValueOf(v).Elem()
+		return reflect.ValueOf(v)
 	}
+	return reflect.ValueOf(v).Elem()
+}
 
-	//fmt.Println("UnmarshalCsv called with separator " + sep) // Debug code
-	parts := strings.Split(data, sep)
+// unmarshalCsvColumns decodes the fields of val from data, taking them from
+// the columns resolved for its type.
+func unmarshalCsvColumns(data string, opts CsvOptions, columns *csvColumns, val reflect.Value) error {
+	//fmt.Println("UnmarshalCsv called with separator " + opts.Separator) // Debug code
+	parts, err := splitCsv(data, opts)
+	if err != nil {
+		return err
+	}
 
 	//fmt.Printf("Found %d fields\n", val.NumField()) // Debug code
 	for i := 0; i < val.NumField(); i++ {
 		typeField := val.Type().Field(i)
 		tag := typeField.Tag
 
-		cField := tag.Get("csv")
+		cField, cFormat := csvTag(tag)
 		cSep := tag.Get("csvsplit")
-		if len(cField) < 1 || len(cField) > 4 {
+		if len(cField) < 1 {
 			//fmt.Println("Bailing out, invalid csv tag ", cField) // Debug code
 			continue
 		}
@@ -60,11 +158,10 @@ func UnmarshalCsv(data string, sep string, v interface{}) error {
 			continue
 		}
 
-		f, _ := strconv.Atoi(cField)
-		//f--
+		f := columns.index[i]
 
 		// Sanity check range before dying miserably
-		if f < 0 || f > len(parts) {
+		if f < 0 || f >= len(parts) {
 			//fmt.Printf("Failed sanity check for f = %d, len(parts) = %d\n", f, len(parts)) // Debug code
 			continue
 		}
@@ -74,99 +171,221 @@ func UnmarshalCsv(data string, sep string, v interface{}) error {
 
 		//fmt.Printf("Field found of type %s\n", typeField.Type.Kind()) // Debug code
 
+		fOpts, err := newFieldOptions(typeField, cFormat, opts.Location)
+		if err != nil {
+			return err
+		}
+		if handled, _ := decodeValue(val.Field(i), s, fOpts); handled {
+			// Values which cannot be parsed are skipped
+			continue
+		}
+
 		switch typeField.Type.Kind() {
-		case reflect.Bool:
-			v, err := strconv.ParseBool(s)
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
+		case reflect.Ptr, reflect.Struct:
+			fixedLayout := tag.Get("csvlayout") == "fixed"
+			if cSep == "" && !fixedLayout {
+				//fmt.Println("No csvsplit defined") // Debug code
 				continue
 			}
-			val.Field(i).SetBool(v)
-			break
-		case reflect.Float32:
-			if DECIMAL_COMMA {
-				s = strings.Replace(s, ",", ".", 1)
+			//fmt.Printf("Found ptr/str value '%s'\n", s) // Debug code
+
+			// Handle embedded objects by recursively parsing
+			// the object with the range we passed.
+			field := val.Field(i)
+			if typeField.Type.Kind() == reflect.Struct {
+				field = field.Addr()
+			} else if field.IsNil() {
+				// Initialize pointer to avoid panic
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			if fixedLayout {
+				// Fixed-length object inside a delimited field
+				err = Unmarshal(s, field.Interface())
+			} else {
+				subOpts := opts
+				subOpts.Separator = cSep
+				subOpts.Header = nil
+				err = UnmarshalCsvOptions(s, subOpts, field.Interface())
 			}
-			v, err := strconv.ParseFloat(s, 32)
 			if err != nil {
 				//fmt.Println(err.Error()) // Debug code
-				continue
 			}
-			val.Field(i).SetFloat(v)
 			break
-		case reflect.Float64:
-			if DECIMAL_COMMA {
-				s = strings.Replace(s, ",", ".", 1)
-			}
-			v, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
-				continue
+		case reflect.Slice:
+			if lOpts, ok := newListOptions(tag, "csvsplit"); ok {
+				err := decodeList(val.Field(i), s, lOpts, fOpts)
+				if err != nil {
+					//fmt.Println(err.Error()) // Debug code
+				}
 			}
-			val.Field(i).SetFloat(v)
 			break
-		case reflect.String:
-			//fmt.Printf("Found string value '%s'\n", s) // Debug code
-			val.Field(i).SetString(s)
+		default:
+			//fmt.Println("Found unknown value '%s'", s) // Debug code
 			break
-		case reflect.Int8:
-			//fmt.Printf("Found value '%s'\n", s) // Debug code
-			v, err := strconv.ParseInt(s, 10, 8)
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
-				continue
+		}
+	}
+	return validateCsvStruct(val, false, opts.Header)
+}
+
+// splitCsv splits a delimited record into its fields, following RFC 4180.
+// Quotes are only special at the beginning of a field.
+func splitCsv(data string, opts CsvOptions) ([]string, error) {
+	sep, quote := csvDelimiters(opts)
+
+	var fields []string
+	for {
+		if opts.TrimLeadingSpace {
+			data = strings.TrimLeft(data, " \t")
+		}
+
+		var field string
+		if strings.HasPrefix(data, quote) {
+			// Quoted field: look for the closing quote, skipping doubled ones
+			var b strings.Builder
+			data = data[len(quote):]
+			for {
+				i := strings.Index(data, quote)
+				if i < 0 {
+					return nil, ErrCsvQuote
+				}
+				b.WriteString(data[:i])
+				data = data[i+len(quote):]
+				if !strings.HasPrefix(data, quote) {
+					break
+				}
+				b.WriteString(quote)
+				data = data[len(quote):]
 			}
-			val.Field(i).SetInt(v)
-			break
-		case reflect.Int32:
-			//fmt.Printf("Found value '%s'\n", s) // Debug code
-			v, err := strconv.ParseInt(s, 10, 32)
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
-				continue
+			if data != "" && !strings.HasPrefix(data, sep) {
+				return nil, ErrCsvQuote
 			}
-			val.Field(i).SetInt(v)
-			break
-		case reflect.Int, reflect.Int64:
-			//fmt.Printf("Found value '%s'\n", s) // Debug code
-			v, err := strconv.ParseInt(s, 10, 64)
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
-				continue
+			field = b.String()
+		} else {
+			i := strings.Index(data, sep)
+			if i < 0 {
+				i = len(data)
 			}
-			val.Field(i).SetInt(v)
-			break
-		case reflect.Uint:
-			//fmt.Printf("Found uint value '%s'\n", s) // Debug code
-			v, err := strconv.ParseUint(s, 10, 64)
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
-				continue
+			field, data = data[:i], data[i:]
+		}
+
+		fields = append(fields, field)
+		if data == "" {
+			return fields, nil
+		}
+		data = data[len(sep):]
+	}
+}
+
+// csvQuoteOpen reports whether data ends inside a quoted field, reading
+// fields as splitCsv does, so that the record goes on with the next line.
+func csvQuoteOpen(data string, opts CsvOptions) bool {
+	sep, quote := csvDelimiters(opts)
+	for {
+		if opts.TrimLeadingSpace {
+			data = strings.TrimLeft(data, " \t")
+		}
+		if strings.HasPrefix(data, quote) {
+			data = data[len(quote):]
+			for {
+				i := strings.Index(data, quote)
+				if i < 0 {
+					return true
+				}
+				data = data[i+len(quote):]
+				if !strings.HasPrefix(data, quote) {
+					break
+				}
+				data = data[len(quote):]
 			}
-			val.Field(i).SetUint(v)
-			break
-		case reflect.Ptr, reflect.Struct:
-			if cSep == "" {
-				//fmt.Println("No csvsplit defined") // Debug code
-				continue
+		}
+		i := strings.Index(data, sep)
+		if i < 0 {
+			return false
+		}
+		data = data[i+len(sep):]
+	}
+}
+
+// csvDelimiters returns the separator and the quote of opts, or their
+// defaults.
+func csvDelimiters(opts CsvOptions) (sep, quote string) {
+	sep = opts.Separator
+	if sep == "" {
+		sep = ","
+	}
+	quote = string(opts.Quote)
+	if opts.Quote == 0 {
+		quote = `"`
+	}
+	return sep, quote
+}
+
+// csvTag splits a `csv` tag into the column and the format following it.
+func csvTag(tag reflect.StructTag) (string, string) {
+	cArguments := strings.SplitN(tag.Get("csv"), ",", 2)
+	if len(cArguments) > 1 {
+		return cArguments[0], cArguments[1]
+	}
+	return cArguments[0], ""
+}
+
+// csvColumn returns the column index for the content of a `csv` tag, which
+// is either a number or a name to look up in header.
+func csvColumn(cField string, header []string) (int, bool) {
+	if strings.HasPrefix(cField, "name=") {
+		name := cField[len("name="):]
+		for i := range header {
+			if header[i] == name {
+				return i, true
 			}
-			//fmt.Printf("Found ptr/str value '%s'\n", s) // Debug code
+		}
+		return -1, false
+	}
+	f, err := strconv.Atoi(cField)
+	return f, err == nil
+}
 
-			// Handle embedded objects by recursively parsing
-			// the object with the range we passed.
-			if val.Field(i).IsNil() {
-				// Initialize pointer to avoid panic
-				val.Field(i).Set(reflect.New(val.Field(i).Type().Elem()))
+// csvColumns maps the fields of a struct type to the columns of a header.
+type csvColumns struct {
+	t     reflect.Type
+	index []int // Column of every field, -1 if none
+}
+
+// resolveCsvColumns finds the columns of the fields of t. With a header,
+// it verifies that the header holds the columns required by the struct
+// and, if asked, only the columns mapped to its fields.
+func resolveCsvColumns(t reflect.Type, opts CsvOptions) (*csvColumns, error) {
+	columns := &csvColumns{t: t, index: make([]int, t.NumField())}
+	mapped := make([]bool, len(opts.Header))
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		cField, _ := csvTag(tag)
+		f, ok := csvColumn(cField, opts.Header)
+		if !ok {
+			f = -1
+		}
+		columns.index[i] = f
+		if ok {
+			if f >= 0 && f < len(mapped) {
+				mapped[f] = true
 			}
-			err := UnmarshalCsv(s, cSep, val.Field(i).Interface())
-			if err != nil {
-				//fmt.Println(err.Error()) // Debug code
+			continue
+		}
+		if opts.Header == nil || !strings.HasPrefix(cField, "name=") {
+			continue
+		}
+		for _, rule := range splitRules(tag.Get("validate")) {
+			if rule == "required" {
+				return nil, &ColumnError{Column: cField[len("name="):], Err: ErrMissingColumn}
+			}
+		}
+	}
+	if opts.Header != nil && opts.DisallowUnknownColumns {
+		for i := range mapped {
+			if !mapped[i] {
+				return nil, &ColumnError{Column: opts.Header[i], Err: ErrUnknownColumn}
 			}
-			break
-		default:
-			//fmt.Println("Found unknown value '%s'", s) // Debug code
-			break
 		}
 	}
-	return nil
+	return columns, nil
 }
@@ -1,6 +1,9 @@
 package gofixedlength
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 const (
 	csvBasicParseTestString   = "1,2,ABC,XYZ"
@@ -68,3 +71,188 @@ func TestCsvLayeredParsing(t *testing.T) {
 		t.Errorf("RawLine parsed as '%s'", out.RawLine)
 	}
 }
+
+func TestCsvQuotedParsing(t *testing.T) {
+	var out csvBasicParseTest
+	data := "1, 2,\"A,\"\"B\"\"\nC\",'X;Y'"
+	if err := UnmarshalCsv(data, ",", &out); err != nil {
+		t.Fatalf("UnmarshalCsv failed: %v", err)
+	}
+	if out.StringC != "A,\"B\"\nC" {
+		t.Errorf("StringC parsed as '%s'", out.StringC)
+	}
+	if out.StringD != "'X;Y'" {
+		t.Errorf("StringD parsed as '%s'", out.StringD)
+	}
+	if out.RawLine != data {
+		t.Errorf("RawLine parsed as '%s'", out.RawLine)
+	}
+
+	out = csvBasicParseTest{}
+	opts := CsvOptions{Separator: ";", Quote: '\'', TrimLeadingSpace: true}
+	if err := UnmarshalCsvOptions("1; 2;'A;''B''';  'X'", opts, &out); err != nil {
+		t.Fatalf("UnmarshalCsvOptions failed: %v", err)
+	}
+	if out.NumberB != 2 || out.StringC != "A;'B'" || out.StringD != "X" {
+		t.Errorf("Parsed as %+v", out)
+	}
+
+	for _, bad := range []string{"1,2,\"ABC", "1,2,\"AB\"C,D"} {
+		if err := UnmarshalCsv(bad, ",", &out); err != ErrCsvQuote {
+			t.Errorf("%s: expected ErrCsvQuote, got %v", bad, err)
+		}
+	}
+}
+
+func TestCsvShortRecord(t *testing.T) {
+	var out csvBasicParseTest
+	if err := UnmarshalCsv("1,2", ",", &out); err != nil {
+		t.Fatalf("UnmarshalCsv failed: %v", err)
+	}
+	if out.NumberB != 2 || out.StringC != "" {
+		t.Errorf("Parsed as %+v", out)
+	}
+}
+
+type csvNamedParseTest struct {
+	Account string  `csv:"name=AccountID" validate:"required"`
+	Amount  float64 `csv:"name=Amount"`
+	Note    string  `csv:"name=Note"`
+	First   string  `csv:"0"`
+}
+
+func TestCsvNamedColumns(t *testing.T) {
+	header, err := ParseCsvHeader("Amount, AccountID ,Extra", CsvOptions{})
+	if err != nil {
+		t.Fatalf("ParseCsvHeader failed: %v", err)
+	}
+	opts := CsvOptions{Header: header}
+	var out csvNamedParseTest
+	if err := UnmarshalCsvOptions("12.5,ACC1,x", opts, &out); err != nil {
+		t.Fatalf("UnmarshalCsvOptions failed: %v", err)
+	}
+	if out.Account != "ACC1" || out.Amount != 12.5 || out.Note != "" || out.First != "12.5" {
+		t.Errorf("Parsed as %+v", out)
+	}
+
+	line, err := MarshalCsvOptions(out, opts)
+	if err != nil || line != "12.5,ACC1," {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+
+	opts.DisallowUnknownColumns = true
+	err = UnmarshalCsvOptions("12.5,ACC1,x", opts, &out)
+	if colErr, ok := err.(*ColumnError); !ok || colErr.Err != ErrUnknownColumn || colErr.Column != "Extra" {
+		t.Errorf("Expected unknown column error, got %v", err)
+	}
+
+	opts = CsvOptions{Header: []string{"Amount"}}
+	err = UnmarshalCsvOptions("12.5", opts, &out)
+	if colErr, ok := err.(*ColumnError); !ok || colErr.Err != ErrMissingColumn || colErr.Column != "AccountID" {
+		t.Errorf("Expected missing column error, got %v", err)
+	}
+}
+
+type csvTimeParseTest struct {
+	Date     time.Time  `csv:"0,2006-01-02"`
+	Stamp    *time.Time `csv:"1,2006-01-02 15:04"`
+	Local    time.Time  `csv:"2,2006-01-02 15:04" tz:"Europe/Rome"`
+	Default  time.Time  `csv:"3"`
+	Optional *time.Time `csv:"4,2006-01-02"`
+}
+
+func TestCsvTimeParsing(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Time zone database not available")
+	}
+	var out csvTimeParseTest
+	data := "2009-10-10,2015-01-14 10:30,2015-01-14 10:30,2015-01-14T10:30:00Z,"
+	if err := UnmarshalCsvOptions(data, CsvOptions{Location: ny}, &out); err != nil {
+		t.Fatalf("UnmarshalCsvOptions failed: %v", err)
+	}
+	if out.Date.Year() != 2009 || out.Date.Month() != 10 || out.Date.Day() != 10 || out.Date.Location() != ny {
+		t.Errorf("Date parsed as %v", out.Date)
+	}
+	if out.Stamp == nil || out.Stamp.Hour() != 10 || out.Stamp.Location() != ny {
+		t.Errorf("Stamp parsed as %v", out.Stamp)
+	}
+	if out.Local.UTC().Hour() != 9 {
+		t.Errorf("Local parsed as %v", out.Local)
+	}
+	if !out.Default.Equal(time.Date(2015, 1, 14, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("Default parsed as %v", out.Default)
+	}
+	if out.Optional != nil {
+		t.Errorf("Optional parsed as %v", out.Optional)
+	}
+
+	line, err := MarshalCsv(out, ",")
+	if err != nil || line != data {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+}
+
+type hybridDetail struct {
+	Branch string `fixed:"0-3"`
+	Amount int    `fixed:"3-8"`
+}
+
+type hybridTags struct {
+	First  string `csv:"0"`
+	Second string `csv:"1"`
+}
+
+type hybridCsvTest struct {
+	Code   string        `csv:"0"`
+	Detail hybridDetail  `csv:"1" csvlayout:"fixed"`
+	Ptr    *hybridDetail `csv:"2" csvlayout:"fixed"`
+}
+
+type hybridFixedTest struct {
+	Code string      `fixed:"0-2"`
+	Tags *hybridTags `fixed:"2-12" fixedsplit:";"`
+}
+
+func TestHybridLayouts(t *testing.T) {
+	var out hybridCsvTest
+	data := "A1,00100025,00200125"
+	if err := UnmarshalCsv(data, ",", &out); err != nil {
+		t.Fatalf("UnmarshalCsv failed: %v", err)
+	}
+	if out.Detail.Branch != "001" || out.Detail.Amount != 25 || out.Ptr == nil || out.Ptr.Amount != 125 {
+		t.Errorf("Parsed as %+v, %+v", out.Detail, out.Ptr)
+	}
+	line, err := MarshalCsv(out, ",")
+	if err != nil || line != "A1,00100025,00200125" {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+
+	var fixedOut hybridFixedTest
+	if err := Unmarshal("XYred;blue  ", &fixedOut); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if fixedOut.Tags == nil || fixedOut.Tags.First != "red" || fixedOut.Tags.Second != "blue" {
+		t.Errorf("Parsed as %+v", fixedOut.Tags)
+	}
+	line, err = Marshal(fixedOut)
+	if err != nil || line != "XYred;blue  " {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+}
+
+func TestCsvHeaderChange(t *testing.T) {
+	opts := CsvOptions{Header: []string{"Amount", "Note"}}
+	for i := 0; i < 2; i++ {
+		var out csvNamedParseTest
+		err := UnmarshalCsvOptions("1,x", opts, &out)
+		if colErr, ok := err.(*ColumnError); !ok || colErr.Err != ErrMissingColumn {
+			t.Errorf("Call %d: expected ErrMissingColumn, got %v", i, err)
+		}
+	}
+	opts.Header = []string{"AccountID", "Note"}
+	var out csvNamedParseTest
+	if err := UnmarshalCsvOptions("A,x", opts, &out); err != nil || out.Note != "x" {
+		t.Errorf("Parsed as %+v (%v)", out, err)
+	}
+}
@@ -0,0 +1,196 @@
+package gofixedlength
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// CsvDecoder reads delimited records from an input stream, skipping blank
+// and comment lines. Quoted fields can span several lines.
+type CsvDecoder struct {
+	r          *bufio.Reader
+	opts       CsvStreamOptions
+	progress   Progress // Records decoded, header aside
+	headerRead bool
+	columns    *csvColumns // Columns of the last decoded type
+}
+
+// NewCsvDecoder returns a decoder reading from r. When opts.HasHeader is
+// set, the first record is read as the header and used to resolve the
+// `csv:"name=..."` tags.
+func NewCsvDecoder(r io.Reader, opts CsvStreamOptions) *CsvDecoder {
+	return &CsvDecoder{r: bufio.NewReader(r), opts: opts}
+}
+
+// Header returns the header of the stream, reading it if needed.
+func (d *CsvDecoder) Header() ([]string, error) {
+	if d.opts.HasHeader && !d.headerRead {
+		d.headerRead = true
+		record, line, err := d.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		header, err := ParseCsvHeader(record, d.opts.CsvOptions)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		d.opts.Header = header
+	}
+	return d.opts.Header, nil
+}
+
+// Decode reads the next record and unmarshals it into v as
+// UnmarshalCsvOptions does, the columns of the header being resolved once
+// for the type of v. It returns io.EOF when there are no more records;
+// other errors are *LineError values giving the line the record starts at.
+func (d *CsvDecoder) Decode(v interface{}) error {
+	if _, err := d.Header(); err != nil {
+		return err
+	}
+	record, line, err := d.readRecord()
+	if err != nil {
+		return err
+	}
+	val := csvTarget(v)
+	if d.columns == nil || d.columns.t != val.Type() {
+		columns, err := resolveCsvColumns(val.Type(), d.opts.CsvOptions)
+		if err != nil {
+			return &LineError{Line: line, Err: err}
+		}
+		d.columns = columns
+	}
+	d.progress.Records++
+	if err := unmarshalCsvColumns(record, d.opts.CsvOptions, d.columns, val); err != nil {
+		return &LineError{Line: line, Err: err}
+	}
+	return nil
+}
+
+// DecodeContext is like Decode, but returns a *ProgressError instead of
+// reading further once ctx is done. A read blocked on the underlying
+// reader is not interrupted.
+func (d *CsvDecoder) DecodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return &ProgressError{Progress: d.progress, Err: err}
+	}
+	return d.Decode(v)
+}
+
+// Progress returns the records decoded so far, the lines read and the
+// offset of the next line in the input.
+func (d *CsvDecoder) Progress() Progress {
+	return d.progress
+}
+
+// readRecord returns the next record, without its line terminator, and the
+// line it starts at. Line breaks inside quoted fields are kept as found.
+func (d *CsvDecoder) readRecord() (string, int, error) {
+	for {
+		record, err := d.readLine()
+		if err != nil {
+			return "", 0, err
+		}
+		start := d.progress.Lines
+		if strings.TrimSpace(record) == "" || d.opts.Comment != 0 && strings.HasPrefix(record, string(d.opts.Comment)) {
+			continue
+		}
+		// A quoted field left open goes on with the next line
+		for csvQuoteOpen(record, d.opts.CsvOptions) {
+			next, err := d.readLine()
+			if err == io.EOF {
+				return "", 0, &LineError{Line: start, Err: ErrCsvQuote}
+			}
+			if err != nil {
+				return "", 0, err
+			}
+			record += next
+		}
+		record = strings.TrimSuffix(record, "\n")
+		return strings.TrimSuffix(record, "\r"), start, nil
+	}
+}
+
+// readLine reads a line, including its line terminator.
+func (d *CsvDecoder) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	d.progress.Lines++
+	d.progress.Offset += int64(len(line))
+	return line, nil
+}
+
+// CsvEncoder writes delimited records to an output stream.
+type CsvEncoder struct {
+	w             *bufio.Writer
+	opts          CsvStreamOptions
+	headerWritten bool
+}
+
+// NewCsvEncoder returns an encoder writing to w. When opts.HasHeader is set,
+// a header is written before the first record: opts.Header if given,
+// otherwise the one returned by CsvHeaderOf for the first value.
+func NewCsvEncoder(w io.Writer, opts CsvStreamOptions) *CsvEncoder {
+	return &CsvEncoder{w: bufio.NewWriter(w), opts: opts}
+}
+
+// Encode marshals v with MarshalCsvOptions and writes it as a record.
+func (e *CsvEncoder) Encode(v interface{}) error {
+	eol := e.opts.EOL
+	if eol == "" {
+		eol = EOL_UNIX
+	}
+	if e.opts.HasHeader && !e.headerWritten {
+		e.headerWritten = true
+		if e.opts.Header == nil {
+			e.opts.Header = CsvHeaderOf(v)
+		}
+		if _, err := e.w.WriteString(joinCsv(e.opts.Header, e.opts.CsvOptions) + eol); err != nil {
+			return err
+		}
+	}
+	record, err := MarshalCsvOptions(v, e.opts.CsvOptions)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.WriteString(record + eol)
+	return err
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (e *CsvEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// CsvHeaderOf returns a header for an annotated struct: fields tagged with
+// an index are named after the struct field, at their index, while named
+// columns follow in declaration order.
+func CsvHeaderOf(v interface{}) []string {
+	t := reflect.Indirect(reflect.ValueOf(v)).Type()
+	var header, named []string
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+		cField, _ := csvTag(typeField.Tag)
+		if strings.HasPrefix(cField, "name=") {
+			named = append(named, cField[len("name="):])
+			continue
+		}
+		f, err := strconv.Atoi(cField)
+		if err != nil || f < 0 {
+			continue
+		}
+		for len(header) <= f {
+			header = append(header, "")
+		}
+		header[f] = typeField.Name
+	}
+	return append(header, named...)
+}
@@ -0,0 +1,156 @@
+package gofixedlength
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const csvStreamTestData = "# Exported accounts\r\nAmount;AccountID;Note\r\n\r\n12.5;ACC1;\"multi\r\nline\"\r\n# Comment\r\n3;ACC2;plain\r\n"
+
+type csvStreamTest struct {
+	Account string  `csv:"name=AccountID" validate:"required"`
+	Amount  float64 `csv:"name=Amount"`
+	Note    string  `csv:"name=Note"`
+}
+
+func TestCsvDecoder(t *testing.T) {
+	d := NewCsvDecoder(strings.NewReader(csvStreamTestData), CsvStreamOptions{CsvOptions: CsvOptions{Separator: ";"}, HasHeader: true, Comment: '#'})
+	var out []csvStreamTest
+	for {
+		var rec csvStreamTest
+		err := d.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		out = append(out, rec)
+	}
+	if len(out) != 2 {
+		t.Fatalf("Decoded %d records", len(out))
+	}
+	if out[0].Account != "ACC1" || out[0].Amount != 12.5 || out[0].Note != "multi\r\nline" {
+		t.Errorf("First record decoded as %+v", out[0])
+	}
+	if out[1].Account != "ACC2" || out[1].Note != "plain" {
+		t.Errorf("Second record decoded as %+v", out[1])
+	}
+	if header, _ := d.Header(); len(header) != 3 || header[1] != "AccountID" {
+		t.Errorf("Header read as %v", header)
+	}
+}
+
+func TestCsvDecoderErrors(t *testing.T) {
+	d := NewCsvDecoder(strings.NewReader("Amount,AccountID\n1,A\n\n2,\n"), CsvStreamOptions{HasHeader: true})
+	var rec csvStreamTest
+	if err := d.Decode(&rec); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	err := d.Decode(&rec)
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 4 {
+		t.Fatalf("Expected an error on line 4, got %v", err)
+	}
+	if _, ok := lineErr.Err.(ValidationErrors); !ok {
+		t.Errorf("Expected validation errors, got %v", lineErr.Err)
+	}
+
+	d = NewCsvDecoder(strings.NewReader("Amount\n1\n"), CsvStreamOptions{HasHeader: true})
+	if err := d.Decode(&rec); !errors.Is(err, ErrMissingColumn) {
+		t.Errorf("Expected ErrMissingColumn, got %v", err)
+	}
+}
+
+type csvQuoteTest struct {
+	A int    `csv:"0"`
+	B string `csv:"1"`
+}
+
+func TestCsvDecoderQuotes(t *testing.T) {
+	// A quote inside an unquoted field does not open a quoted field
+	d := NewCsvDecoder(strings.NewReader("1,5\" screen\n2,\"two\nlines\"\n3,fine\n"), CsvStreamOptions{})
+	var out []csvQuoteTest
+	for {
+		var rec csvQuoteTest
+		err := d.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		out = append(out, rec)
+	}
+	if len(out) != 3 || out[0].B != "5\" screen" || out[1].B != "two\nlines" || out[2].A != 3 || out[2].B != "fine" {
+		t.Errorf("Decoded as %+v", out)
+	}
+
+	d = NewCsvDecoder(strings.NewReader("1,ok\n2,\"open\nstill open\n"), CsvStreamOptions{})
+	var rec csvQuoteTest
+	if err := d.Decode(&rec); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	var lineErr *LineError
+	if err := d.Decode(&rec); !errors.As(err, &lineErr) || lineErr.Line != 2 || lineErr.Err != ErrCsvQuote {
+		t.Errorf("Expected ErrCsvQuote on line 2, got %v", err)
+	}
+}
+
+func TestCsvDecoderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := NewCsvDecoder(strings.NewReader("A,B\n1,x\n\n2,\"y\nz\"\n3,w\n"), CsvStreamOptions{HasHeader: true})
+	var rec csvQuoteTest
+	for i := 0; i < 2; i++ {
+		if err := d.DecodeContext(ctx, &rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	err := d.DecodeContext(ctx, &rec)
+	var progressErr *ProgressError
+	if !errors.As(err, &progressErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a ProgressError, got %v", err)
+	}
+	if progressErr.Progress != (Progress{Records: 2, Lines: 5, Offset: 17}) || rec.A != 2 {
+		t.Errorf("Expected 2 records up to offset 17, got %+v", progressErr.Progress)
+	}
+}
+
+func TestCsvEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewCsvEncoder(&buf, CsvStreamOptions{CsvOptions: CsvOptions{Separator: ";"}, HasHeader: true, EOL: EOL_DOS})
+	for _, rec := range []csvStreamTest{{"ACC1", 12.5, "multi\r\nline"}, {"ACC2", 3, "plain"}} {
+		if err := e.Encode(rec); err != nil {
+			t.Fatalf("Encode failed: %v", err)
+		}
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	expected := "AccountID;Amount;Note\r\nACC1;12.5;\"multi\r\nline\"\r\nACC2;3;plain\r\n"
+	if buf.String() != expected {
+		t.Errorf("Encoded as %q", buf.String())
+	}
+
+	// Rows span the given header
+	buf.Reset()
+	e = NewCsvEncoder(&buf, CsvStreamOptions{CsvOptions: CsvOptions{Header: []string{"AccountID", "Extra", "Amount", "Note", "Tail"}}, HasHeader: true})
+	if err := e.Encode(csvStreamTest{"ACC2", 3, "plain"}); err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	e.Flush()
+	if buf.String() != "AccountID,Extra,Amount,Note,Tail\nACC2,,3,plain,\n" {
+		t.Errorf("Encoded as %q", buf.String())
+	}
+
+	header := CsvHeaderOf(csvBasicParseTest{})
+	if strings.Join(header, ",") != "NumberA,NumberB,StringC,StringD" {
+		t.Errorf("Header built as %v", header)
+	}
+}
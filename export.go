@@ -0,0 +1,118 @@
+package gofixedlength
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// LayoutOf returns the layout of an annotated struct, reading its `fixed`
+// tags as Unmarshal and LineLength do: the fields of embedded structs with
+// a range are listed with their absolute ranges and dotted names, while
+// untagged fields are left out. Values which have no FieldType,
+// like lists, are described as strings holding their text, so that the
+// layout remains a valid RecordLayout.
+func LayoutOf(v interface{}) Layout {
+	return layoutOf(reflect.Indirect(reflect.ValueOf(v)).Type(), "", 0)
+}
+
+func layoutOf(t reflect.Type, prefix string, offset int) Layout {
+	var layout Layout
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+		b, e, cFormat, ok := fixedTag(typeField.Tag)
+		if !ok {
+			continue
+		}
+
+		sub := typeField.Type
+		if sub.Kind() == reflect.Ptr {
+			sub = sub.Elem()
+		}
+		if sub.Kind() == reflect.Struct && !isTimeType(typeField.Type) && typeField.Tag.Get("fixedsplit") == "" {
+			layout = append(layout, layoutOf(sub, prefix+typeField.Name+".", offset+b)...)
+			continue
+		}
+
+		f := LayoutField{
+			Name:   prefix + typeField.Name,
+			Begin:  b + offset,
+			End:    e + offset,
+			Type:   fieldTypeOf(typeField.Type),
+			Format: cFormat,
+			TZ:     typeField.Tag.Get("tz"),
+		}
+		if f.Type == TypeFloat && f.Format == "" {
+			f.Format = "2" // As printed by Marshal
+		}
+		layout = append(layout, f)
+	}
+	return layout
+}
+
+// fieldTypeOf returns the FieldType of a Go type, TypeString if it has
+// none.
+func fieldTypeOf(t reflect.Type) FieldType {
+	if isTimeType(t) {
+		return TypeTime
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return TypeString
+	case reflect.Bool:
+		return TypeBool
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return TypeInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeUint
+	}
+	return TypeString
+}
+
+// WriteJSON writes the layout as an indented JSON document, which can be
+// used as the fields of a RecordLayout.
+func (layout Layout) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(layout, "", "\t")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteMarkdown writes the layout as a Markdown table. Start offsets are
+// zero based and end offsets are excluded, as in the `fixed` tags.
+func (layout Layout) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("| Field | Start | End | Width | Type | Format |\n")
+	b.WriteString("|-------|------:|----:|------:|------|--------|\n")
+	for _, f := range layout {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %s | %s |\n", markdownEscape(f.Name), f.Begin, f.End, f.End-f.Begin,
+			markdownEscape(string(f.Type)), markdownEscape(f.Format))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownEscape(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+// WriteHTML writes the layout as an HTML table, with the same columns as
+// WriteMarkdown.
+func (layout Layout) WriteHTML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("<table>\n<tr><th>Field</th><th>Start</th><th>End</th><th>Width</th><th>Type</th><th>Format</th></tr>\n")
+	for _, f := range layout {
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td><td>%s</td></tr>\n", html.EscapeString(f.Name),
+			f.Begin, f.End, f.End-f.Begin, html.EscapeString(string(f.Type)), html.EscapeString(f.Format))
+	}
+	b.WriteString("</table>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,103 @@
+package gofixedlength
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+type exportNested struct {
+	Branch string `fixed:"0-3"`
+	Number int    `fixed:"3-8"`
+}
+
+type exportCommon struct {
+	Kind string `fixed:"0-1"`
+}
+
+type exportTest struct {
+	exportCommon
+	Date    time.Time    `fixed:"1-9,20060102" tz:"Europe/Rome"`
+	Account exportNested `fixed:"9-17"`
+	Amount  float64      `fixed:"17-27"`
+	Codes   []string     `fixed:"27-37" fixedsplit:";"`
+}
+
+func TestLayoutOf(t *testing.T) {
+	layout := LayoutOf(&exportTest{})
+	expected := Layout{
+		{Name: "Date", Begin: 1, End: 9, Type: TypeTime, Format: "20060102", TZ: "Europe/Rome"},
+		{Name: "Account.Branch", Begin: 9, End: 12, Type: TypeString},
+		{Name: "Account.Number", Begin: 12, End: 17, Type: TypeInt},
+		{Name: "Amount", Begin: 17, End: 27, Type: TypeFloat, Format: "2"},
+		{Name: "Codes", Begin: 27, End: 37, Type: TypeString},
+	}
+	if len(layout) != len(expected) {
+		t.Fatalf("Layout is %+v", layout)
+	}
+	for i := range expected {
+		if layout[i] != expected[i] {
+			t.Errorf("Field %d is %+v, expected %+v", i, layout[i], expected[i])
+		}
+	}
+	if layout.Length() != LineLength(exportTest{}) {
+		t.Errorf("Layout length %d differs from LineLength %d", layout.Length(), LineLength(exportTest{}))
+	}
+
+	// The exported layout decodes records like the struct
+	values, err := UnmarshalToMap("A20150114001000420000012.50", layout[2:5])
+	if err != nil || values["Account.Number"] != 42 || values["Amount"] != 12.5 {
+		t.Errorf("Decoded as %v (%v)", values, err)
+	}
+}
+
+func TestLayoutDocuments(t *testing.T) {
+	layout := LayoutOf(exportNested{})
+
+	var b bytes.Buffer
+	if err := layout.WriteMarkdown(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "| Number | 3 | 8 | 5 | int |  |\n") {
+		t.Errorf("Markdown is %s", b.String())
+	}
+
+	b.Reset()
+	if err := layout.WriteHTML(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<tr><td>Branch</td><td>0</td><td>3</td><td>3</td><td>string</td><td></td></tr>") {
+		t.Errorf("HTML is %s", b.String())
+	}
+
+	b.Reset()
+	if err := layout.WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchema(strings.NewReader(`{"records": [{"name": "r", "fields": ` + b.String() + `}]}`))
+	if err != nil || len(s.Records[0].Fields) != 2 || s.Records[0].Fields[1] != layout[1] {
+		t.Errorf("JSON is %s (%v)", b.String(), err)
+	}
+}
+
+func TestLayoutSchemaRoundTrip(t *testing.T) {
+	var b bytes.Buffer
+	if err := LayoutOf(exportTest{}).WriteJSON(&b); err != nil {
+		t.Fatal(err)
+	}
+	s, err := LoadSchema(strings.NewReader(`{"records": [{"name": "r", "fields": ` + b.String() + `}]}`))
+	if err != nil {
+		t.Fatalf("Exported layout rejected: %v", err)
+	}
+
+	data := "A20150114001000420000012.50a;b;c     "
+	var out exportTest
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := s.Unmarshal(data)
+	if err != nil || rec.Values["Codes"] != "a;b;c" || rec.Values["Account.Number"] != out.Account.Number || len(out.Codes) != 3 {
+		t.Errorf("Decoded as %v (%v), expected %+v", rec.Values, err, out)
+	}
+}
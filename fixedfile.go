@@ -0,0 +1,110 @@
+package gofixedlength
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+var (
+	ErrRecordIndex = errors.New("Record index is out of range")
+	ErrUnknownSize = errors.New("Size of the input cannot be found")
+)
+
+// FixedFile gives random access to records of a fixed size stored back to
+// back, without line terminators, in an io.ReaderAt such as an *os.File or
+// a memory-mapped file. It is safe for concurrent use if the underlying
+// reader is.
+type FixedFile struct {
+	r    io.ReaderAt
+	size int
+	n    int
+}
+
+// NewFixedFile returns the records of size bytes found in r, usually
+// LineLength of their type. The size of the input is found through a
+// Size, Len or Stat method, as provided by *os.File, *bytes.Reader,
+// *io.SectionReader or mmap.ReaderAt. A trailing partial record is
+// ignored.
+func NewFixedFile(r io.ReaderAt, size int) (*FixedFile, error) {
+	var length int64
+	switch x := r.(type) {
+	case interface{ Size() int64 }:
+		length = x.Size()
+	case interface{ Len() int }:
+		length = int64(x.Len())
+	case interface{ Stat() (os.FileInfo, error) }:
+		info, err := x.Stat()
+		if err != nil {
+			return nil, err
+		}
+		length = info.Size()
+	default:
+		return nil, ErrUnknownSize
+	}
+	return NewFixedFileSize(r, size, length), nil
+}
+
+// NewFixedFileSize is like NewFixedFile, for an input of length bytes.
+func NewFixedFileSize(r io.ReaderAt, size int, length int64) *FixedFile {
+	f := &FixedFile{r: r, size: size}
+	if size > 0 {
+		f.n = int(length / int64(size))
+	}
+	return f
+}
+
+// Len returns the number of records.
+func (f *FixedFile) Len() int {
+	return f.n
+}
+
+// RecordAt returns the record at index, counting from zero.
+func (f *FixedFile) RecordAt(index int) ([]byte, error) {
+	if index < 0 || index >= f.n {
+		return nil, ErrRecordIndex
+	}
+	record := make([]byte, f.size)
+	if _, err := f.r.ReadAt(record, int64(index)*int64(f.size)); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return record, nil
+}
+
+// DecodeAt unmarshals the record at index into v with UnmarshalBytes.
+// Decoding errors are *LineError values, lines counting from one.
+func (f *FixedFile) DecodeAt(index int, v interface{}) error {
+	record, err := f.RecordAt(index)
+	if err != nil {
+		return err
+	}
+	if err := UnmarshalBytes(record, v); err != nil {
+		return &LineError{Line: index + 1, Err: err}
+	}
+	return nil
+}
+
+// Search returns the smallest index for which less returns false, or Len
+// if there is none, as sort.Search does. Records must be sorted so that
+// less returns true for the records before the sought one, as when
+// comparing their key with the one sought:
+//
+//	i, err := f.Search(func(record []byte) bool {
+//		return string(record[0:10]) < key
+//	})
+func (f *FixedFile) Search(less func(record []byte) bool) (int, error) {
+	i, j := 0, f.n
+	for i < j {
+		h := int(uint(i+j) >> 1)
+		record, err := f.RecordAt(h)
+		if err != nil {
+			return 0, err
+		}
+		if less(record) {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	return i, nil
+}
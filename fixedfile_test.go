@@ -0,0 +1,81 @@
+package gofixedlength
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// readerAt hides the size of a reader.
+type readerAt struct {
+	r *strings.Reader
+}
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) {
+	return r.r.ReadAt(p, off)
+}
+
+func TestFixedFile(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		code := "A"
+		if i == 50 {
+			code = "X"
+		}
+		fmt.Fprintf(&b, "%s%06d", code, i*2)
+	}
+	b.WriteString("A00")
+	data := b.String()
+
+	file, err := ioutil.TempFile("", "fixedfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+
+	size := LineLength(parallelTest{})
+	if _, err := NewFixedFile(readerAt{strings.NewReader(data)}, size); err != ErrUnknownSize {
+		t.Errorf("Expected ErrUnknownSize, got %v", err)
+	}
+	fromFile, err := NewFixedFile(file, size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromString, err := NewFixedFile(strings.NewReader(data), size)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range []*FixedFile{fromFile, fromString, NewFixedFileSize(readerAt{strings.NewReader(data)}, size, int64(len(data)))} {
+		if f.Len() != 100 {
+			t.Errorf("Expected 100 records, got %d", f.Len())
+		}
+		var v parallelTest
+		if err := f.DecodeAt(99, &v); err != nil || v.Index != 198 {
+			t.Errorf("Expected record 198, got %+v (%v)", v, err)
+		}
+		var lineErr *LineError
+		if err := f.DecodeAt(50, &v); !errors.As(err, &lineErr) || lineErr.Line != 51 {
+			t.Errorf("Expected a validation error at line 51, got %v", err)
+		}
+		if err := f.DecodeAt(100, &v); err != ErrRecordIndex {
+			t.Errorf("Expected ErrRecordIndex, got %v", err)
+		}
+
+		for key, expected := range map[string]int{"000084": 42, "000085": 43, "000000": 0, "999999": 100} {
+			i, err := f.Search(func(record []byte) bool {
+				return string(record[1:7]) < key
+			})
+			if err != nil || i != expected {
+				t.Errorf("Search %s: expected %d, got %d (%v)", key, expected, i, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package gofixedlength
+
+import (
+	"testing"
+	"time"
+)
+
+//go:generate go run ./cmd/gofixedgen -types genRecord,genChecked,genNoted -o fixedgen_gen_test.go fixedgen_test.go
+
+type genCommon struct {
+	Kind string `fixed:"0-1"`
+}
+
+type genChecked struct {
+	Code string `fixed:"0-1" validate:"oneof=A B"`
+}
+
+type genNested struct {
+	Branch string `fixed:"0-3"`
+	Number uint16 `fixed:"3-8"`
+}
+
+type genRecord struct {
+	Common  genCommon  `fixed:"0-1"`
+	Date    time.Time  `fixed:"1-9,20060102"`
+	Stamp   *time.Time `fixed:"9-21,200601021504"`
+	Account genNested  `fixed:"21-29"`
+	Parent  *genNested `fixed:"29-37"`
+	Amount  float32    `fixed:"37-47,3"`
+	Count   int        `fixed:"47-52"`
+	Paid    bool       `fixed:"52-53"`
+}
+
+// reflectRecord has the layout of genRecord, down to its nested structs,
+// without generated methods
+type reflectRecord struct {
+	Common  reflectCommon  `fixed:"0-1"`
+	Date    time.Time      `fixed:"1-9,20060102"`
+	Stamp   *time.Time     `fixed:"9-21,200601021504"`
+	Account reflectNested  `fixed:"21-29"`
+	Parent  *reflectNested `fixed:"29-37"`
+	Amount  float32        `fixed:"37-47,3"`
+	Count   int            `fixed:"47-52"`
+	Paid    bool           `fixed:"52-53"`
+}
+
+type reflectCommon genCommon
+
+type reflectNested genNested
+
+type genMeta struct {
+	Source string `validate:"required"`
+}
+
+// genNoted only validates through a struct without `fixed` tag
+type genNoted struct {
+	Code string `fixed:"0-3"`
+	Info genMeta
+}
+
+const genData = "A20150114201501141030001000420020000010-00012.50000042 "
+
+func TestGeneratedCodec(t *testing.T) {
+	var generated genRecord
+	var reflected reflectRecord
+	for _, data := range []string{genData, "B2015011x            00100042", ""} {
+		errGenerated := Unmarshal(data, &generated)
+		errReflected := Unmarshal(data, &reflected)
+		if errGenerated != nil || errReflected != nil {
+			t.Fatalf("%q: decoding failed: %v, %v", data, errGenerated, errReflected)
+		}
+		if g, r := mustMarshal(t, generated), mustMarshal(t, reflected); g != r {
+			t.Errorf("%q: generated code gives '%s', reflection '%s'", data, g, r)
+		}
+	}
+
+	var checked genChecked
+	if err := Unmarshal("C", &checked); err == nil {
+		t.Errorf("Expected validation to fail")
+	}
+	if _, err := Marshal(checked); err == nil {
+		t.Errorf("Expected validation to fail")
+	}
+
+	var noted genNoted
+	if err := Unmarshal("ABC", &noted); err == nil {
+		t.Errorf("Expected validation of the untagged struct to fail")
+	}
+	noted.Info.Source = "web"
+	if _, err := Marshal(noted); err != nil {
+		t.Errorf("Valid struct rejected: %v", err)
+	}
+
+	generated.Count = 123456
+	if _, err := Marshal(generated); err != ErrTextTooLongForRange {
+		t.Errorf("Expected ErrTextTooLongForRange, got %v", err)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) string {
+	s, err := Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	return s
+}
+
+func BenchmarkUnmarshalReflect(b *testing.B) {
+	var out reflectRecord
+	for i := 0; i < b.N; i++ {
+		Unmarshal(genData, &out)
+	}
+}
+
+func BenchmarkUnmarshalGenerated(b *testing.B) {
+	var out genRecord
+	for i := 0; i < b.N; i++ {
+		Unmarshal(genData, &out)
+	}
+}
+
+func BenchmarkMarshalReflect(b *testing.B) {
+	var in reflectRecord
+	Unmarshal(genData, &in)
+	for i := 0; i < b.N; i++ {
+		Marshal(in)
+	}
+}
+
+func BenchmarkMarshalGenerated(b *testing.B) {
+	var in genRecord
+	Unmarshal(genData, &in)
+	for i := 0; i < b.N; i++ {
+		Marshal(in)
+	}
+}
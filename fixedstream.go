@@ -0,0 +1,283 @@
+package gofixedlength
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrDecoderStarted = errors.New("Decoder has already read records")
+	ErrCheckpoint     = errors.New("Checkpoint does not match the record size")
+)
+
+// Progress tells how far a decoder or encoder went, so that an interrupted
+// job can report it or be resumed: the progress of a decoder is the
+// checkpoint FixedDecoder.Resume starts from.
+type Progress struct {
+	Records int   // Records read or written
+	Lines   int   // Lines read or written, including empty ones
+	Offset  int64 // Bytes consumed from the input, or written to the output
+}
+
+// ProgressError is returned by the context-aware functions when their
+// context is done, along with the progress made so far.
+type ProgressError struct {
+	Progress
+	Err error // ctx.Err()
+}
+
+func (e *ProgressError) Error() string {
+	return fmt.Sprintf("stopped after %d records (offset %d): %s", e.Records, e.Offset, e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *ProgressError) Unwrap() error {
+	return e.Err
+}
+
+// FixedDecoder reads fixed-length records, one per line, from an input
+// stream. Lines can end with EOL_UNIX or EOL_DOS; empty lines are skipped.
+// Records are read into a reused buffer and decoded with UnmarshalBytes.
+type FixedDecoder struct {
+	src      io.Reader
+	r        *bufio.Reader
+	size     int    // Size of undelimited records, 0 for lines
+	buf      []byte // Records longer than the reader buffer
+	progress Progress
+	controls *ControlAccumulator
+}
+
+// NewFixedDecoder returns a decoder reading from r.
+func NewFixedDecoder(r io.Reader) *FixedDecoder {
+	return &FixedDecoder{src: r, r: bufio.NewReader(r)}
+}
+
+// NewFixedSizeDecoder returns a decoder reading records of size bytes
+// each, back to back without line terminators, as in many mainframe files.
+// size is usually LineLength of the decoded type. Every record counts as a
+// line.
+func NewFixedSizeDecoder(r io.Reader, size int) *FixedDecoder {
+	return &FixedDecoder{src: r, r: bufio.NewReader(r), size: size, buf: make([]byte, size)}
+}
+
+// Resume makes the decoder start from a checkpoint returned by Progress,
+// before any record is read. Offsets count from the position of the
+// input when the decoder was created. The input is seeked to the offset
+// if it is an io.Seeker, and read up to it otherwise.
+//
+// For undelimited records, start can give the number of records only,
+// the offset being start.Records × size.
+func (d *FixedDecoder) Resume(start Progress) error {
+	if d.progress != (Progress{}) || d.r.Buffered() > 0 {
+		return ErrDecoderStarted
+	}
+	if d.size > 0 {
+		offset := int64(start.Records) * int64(d.size)
+		if start.Offset == 0 {
+			start.Offset = offset
+		}
+		if start.Lines == 0 {
+			start.Lines = start.Records
+		}
+		if start.Offset != offset || start.Lines != start.Records {
+			return ErrCheckpoint
+		}
+	}
+
+	if seeker, ok := d.src.(io.Seeker); ok {
+		if _, err := seeker.Seek(start.Offset, io.SeekCurrent); err != nil {
+			return err
+		}
+	} else {
+		// Discard in chunks, as int may not hold large offsets
+		for left := start.Offset; left > 0; {
+			n := left
+			if n > 1<<30 {
+				n = 1 << 30
+			}
+			if _, err := d.r.Discard(int(n)); err != nil {
+				return err
+			}
+			left -= n
+		}
+	}
+	d.progress = start
+	return nil
+}
+
+// Decode reads the next record and unmarshals it into v. It returns io.EOF
+// when there are no more records; other errors are *LineError values.
+func (d *FixedDecoder) Decode(v interface{}) error {
+	record, err := d.ReadRecord()
+	if err != nil {
+		return err
+	}
+	if err := UnmarshalBytes(record, v); err != nil {
+		return &LineError{Line: d.progress.Lines, Err: err}
+	}
+	if d.controls != nil {
+		if err := d.controls.decoded(v); err != nil {
+			return &LineError{Line: d.progress.Lines, Err: err}
+		}
+	}
+	return nil
+}
+
+// SetControls makes the decoder accumulate control totals over the records
+// it decodes. Decoding a control record of acc checks its control fields,
+// giving a *ControlError on mismatch, and starts new totals.
+func (d *FixedDecoder) SetControls(acc *ControlAccumulator) {
+	d.controls = acc
+}
+
+// DecodeContext is like Decode, but returns a *ProgressError instead of
+// reading further once ctx is done. A read blocked on the underlying
+// reader is not interrupted.
+func (d *FixedDecoder) DecodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return &ProgressError{Progress: d.progress, Err: err}
+	}
+	return d.Decode(v)
+}
+
+// ReadRecord returns the next record, without its line terminator. The
+// slice is only valid until the next call. An undelimited record cut
+// short by the end of the input gives io.ErrUnexpectedEOF.
+func (d *FixedDecoder) ReadRecord() ([]byte, error) {
+	for {
+		record, err := d.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(record) > 0 {
+			d.progress.Records++
+			return record, nil
+		}
+	}
+}
+
+// Line returns the number of lines read so far, that is the line of the
+// last record returned.
+func (d *FixedDecoder) Line() int {
+	return d.progress.Lines
+}
+
+// Progress returns the records read so far and the offset of the next
+// line in the input.
+func (d *FixedDecoder) Progress() Progress {
+	return d.progress
+}
+
+func (d *FixedDecoder) readLine() ([]byte, error) {
+	if d.size > 0 {
+		n, err := io.ReadFull(d.r, d.buf)
+		d.progress.Offset += int64(n)
+		if err != nil {
+			return nil, err
+		}
+		d.progress.Lines++
+		return d.buf, nil
+	}
+
+	line, err := d.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// Long record: gather it in our own buffer
+		d.buf = append(d.buf[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = d.r.ReadSlice('\n')
+			d.buf = append(d.buf, line...)
+		}
+		line = d.buf
+	}
+	if err == io.EOF && len(line) > 0 {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.progress.Lines++
+	d.progress.Offset += int64(len(line))
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+// FixedEncoder writes fixed-length records, one per line, to an output
+// stream.
+type FixedEncoder struct {
+	w        *bufio.Writer
+	eol      string
+	progress Progress
+	controls *ControlAccumulator
+}
+
+// NewFixedEncoder returns an encoder writing to w, ending records with
+// eolstyle, or EOL_UNIX if empty.
+func NewFixedEncoder(w io.Writer, eolstyle string) *FixedEncoder {
+	if eolstyle == "" {
+		eolstyle = EOL_UNIX
+	}
+	return &FixedEncoder{w: bufio.NewWriter(w), eol: eolstyle}
+}
+
+// Encode marshals v and writes it as a record. Marshalling errors are
+// *LineError values giving the line the record would have taken.
+func (e *FixedEncoder) Encode(v interface{}) error {
+	if e.controls != nil && e.controls.IsControl(v) {
+		var err error
+		if v, err = e.controls.applied(v); err != nil {
+			return &LineError{Line: e.progress.Records + 1, Err: err}
+		}
+	}
+	record, err := Marshal(v)
+	if err != nil {
+		return &LineError{Line: e.progress.Records + 1, Err: err}
+	}
+	n, err := e.w.WriteString(record + e.eol)
+	e.progress.Offset += int64(n)
+	if err != nil {
+		return err
+	}
+	e.progress.Records++
+	e.progress.Lines++
+	if e.controls != nil {
+		if err := e.controls.encoded(v); err != nil {
+			return &LineError{Line: e.progress.Records, Err: err}
+		}
+	}
+	return nil
+}
+
+// SetControls makes the encoder accumulate control totals over the records
+// it encodes. The control fields of the control records of acc are set to
+// the totals before marshalling, and new totals start after them.
+func (e *FixedEncoder) SetControls(acc *ControlAccumulator) {
+	e.controls = acc
+}
+
+// EncodeContext is like Encode, but returns a *ProgressError instead of
+// writing once ctx is done.
+func (e *FixedEncoder) EncodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return &ProgressError{Progress: e.progress, Err: err}
+	}
+	return e.Encode(v)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (e *FixedEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Progress returns the records written so far and the bytes they took,
+// including those not flushed yet.
+func (e *FixedEncoder) Progress() Progress {
+	return e.progress
+}
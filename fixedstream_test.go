@@ -0,0 +1,114 @@
+package gofixedlength
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFixedDecoderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := NewFixedDecoder(strings.NewReader("A000001\r\n\r\nA000002\nA000003\n"))
+	var v parallelTest
+	for i := 0; i < 2; i++ {
+		if err := d.DecodeContext(ctx, &v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	err := d.DecodeContext(ctx, &v)
+	var progressErr *ProgressError
+	if !errors.As(err, &progressErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a ProgressError, got %v", err)
+	}
+	if progressErr.Progress != (Progress{Records: 2, Lines: 3, Offset: 19}) || v.Index != 2 {
+		t.Errorf("Expected 2 records up to offset 19, got %+v", progressErr.Progress)
+	}
+}
+
+func TestFixedEncoder(t *testing.T) {
+	var b bytes.Buffer
+	e := NewFixedEncoder(&b, EOL_DOS)
+	for i := 1; i <= 2; i++ {
+		if err := e.EncodeContext(context.Background(), parallelTest{Code: "A", Index: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := e.Encode(parallelTest{Code: "X"})
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 3 {
+		t.Errorf("Expected a validation error at line 3, got %v", err)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "A000001\r\nA000002\r\n" || e.Progress() != (Progress{Records: 2, Lines: 2, Offset: 18}) {
+		t.Errorf("Unexpected output %q, progress %+v", b.String(), e.Progress())
+	}
+}
+
+// onlyReader hides the Seek method of a reader.
+type onlyReader struct {
+	r io.Reader
+}
+
+func (o onlyReader) Read(p []byte) (int, error) {
+	return o.r.Read(p)
+}
+
+func TestFixedDecoderResume(t *testing.T) {
+	data := "A000000\r\n\r\nA000001\nX000002\nA000003\n"
+	d := NewFixedDecoder(strings.NewReader(data))
+	var v parallelTest
+	for i := 0; i < 2; i++ {
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkpoint := d.Progress()
+	if err := d.Resume(checkpoint); err != ErrDecoderStarted {
+		t.Errorf("Expected ErrDecoderStarted, got %v", err)
+	}
+
+	for _, r := range []io.Reader{strings.NewReader(data), onlyReader{strings.NewReader(data)}} {
+		d := NewFixedDecoder(r)
+		if err := d.Resume(checkpoint); err != nil {
+			t.Fatal(err)
+		}
+		err := d.Decode(&v)
+		var lineErr *LineError
+		if !errors.As(err, &lineErr) || lineErr.Line != 4 {
+			t.Errorf("Expected a validation error at line 4, got %v", err)
+		}
+		if err := d.Decode(&v); err != nil || v.Index != 3 || d.Progress().Records != 4 {
+			t.Errorf("Expected record 3, got %+v (%v)", v, err)
+		}
+	}
+}
+
+func TestFixedSizeDecoder(t *testing.T) {
+	data := "A000000A000001A000002A00000"
+	d := NewFixedSizeDecoder(strings.NewReader(data), LineLength(parallelTest{}))
+	if err := d.Resume(Progress{Records: 1}); err != nil {
+		t.Fatal(err)
+	}
+	var v parallelTest
+	if err := d.Decode(&v); err != nil || v.Index != 1 {
+		t.Errorf("Expected record 1, got %+v (%v)", v, err)
+	}
+	if err := d.Decode(&v); err != nil || v.Index != 2 || d.Line() != 3 {
+		t.Errorf("Expected record 2 at line 3, got %+v (%v) at line %d", v, err, d.Line())
+	}
+	if err := d.Decode(&v); err != io.ErrUnexpectedEOF {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	d = NewFixedSizeDecoder(strings.NewReader(data), 7)
+	if err := d.Resume(Progress{Records: 1, Offset: 8}); err != ErrCheckpoint {
+		t.Errorf("Expected ErrCheckpoint, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+// Package fixedtag parses the `fixed` struct tags of gofixedlength, so that
+// the package and gofixedgen read them the same way.
+package fixedtag
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Parse parses a `fixed` tag into its range and the format following it.
+// ok is false if the tag has no range.
+func Parse(tag reflect.StructTag) (begin, end int, format string, ok bool) {
+	cArguments := strings.SplitN(tag.Get("fixed"), ",", 2)
+	if len(cArguments) > 1 {
+		format = cArguments[1]
+	}
+	cBookend := strings.Split(cArguments[0], "-")
+	if len(cBookend) != 2 {
+		// If we don't have two values, skip
+		return 0, 0, format, false
+	}
+	begin, _ = strconv.Atoi(cBookend[0])
+	end, _ = strconv.Atoi(cBookend[1])
+	return begin, end, format, true
+}
@@ -0,0 +1,24 @@
+package fixedtag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for tag, expected := range map[reflect.StructTag]struct {
+		begin, end int
+		format     string
+		ok         bool
+	}{
+		`fixed:"0-8,20060102"`: {0, 8, "20060102", true},
+		`fixed:"3-5"`:          {3, 5, "", true},
+		`fixed:"3"`:            {0, 0, "", false},
+		`csv:"1"`:              {0, 0, "", false},
+	} {
+		b, e, format, ok := Parse(tag)
+		if b != expected.begin || e != expected.end || format != expected.format || ok != expected.ok {
+			t.Errorf("%s parsed as %d, %d, %q, %v", tag, b, e, format, ok)
+		}
+	}
+}
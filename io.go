@@ -1,6 +1,11 @@
 package gofixedlength
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"strings"
 )
@@ -12,6 +17,9 @@ const (
 	EOL_MAC = "\r"
 	// EOL_DOS represents DOS/Windows style end of line.
 	EOL_DOS = "\r\n"
+	// EOL_AUTO detects the end of lines, any of the styles above ending a
+	// line.
+	EOL_AUTO = ""
 )
 
 // DECIMAL_COMMA enables the parsing of numeric values having a comma
@@ -19,11 +27,172 @@ const (
 var DECIMAL_COMMA bool
 
 // RecordsFromFile reads a file and splits into single line records, which
-// can be unmarshalled.
+// can be unmarshalled. A file ending with a line terminator gives an empty
+// last record. Files compressed with gzip or bzip2 are decompressed, as by
+// OpenFile.
 func RecordsFromFile(filename string, eolstyle string) ([]string, error) {
-	data, err := ioutil.ReadFile(filename)
+	records, _, err := RecordsFromFileOptions(filename, RecordsOptions{EOL: eolstyle})
+	return records, err
+}
+
+// RecordsOptions tunes RecordsFromFileOptions.
+type RecordsOptions struct {
+	EOL       string // Line terminator, EOL_AUTO by default
+	DropEmpty bool   // Drop the empty last record following a final terminator
+	StripBOM  bool   // Remove a leading UTF-8 byte order mark
+}
+
+// RecordsFromFileOptions is like RecordsFromFile, and also returns the
+// style of end of line found. With EOL_AUTO, lines may end with mixed
+// styles, the one returned being the most frequent.
+func RecordsFromFileOptions(filename string, opts RecordsOptions) ([]string, string, error) {
+	f, err := OpenFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, "", err
+	}
+	records, eol := splitRecords(string(data), opts)
+	return records, eol, nil
+}
+
+// splitRecords splits s into records, returning the style of end of line
+// found.
+func splitRecords(s string, opts RecordsOptions) ([]string, string) {
+	if opts.StripBOM {
+		s = strings.TrimPrefix(s, "\uFEFF")
+	}
+
+	var records []string
+	eol := opts.EOL
+	if eol != EOL_AUTO {
+		records = strings.Split(s, eol)
+	} else {
+		counts := make(map[string]int)
+		start := 0
+		for i := 0; i < len(s); i++ {
+			var style string
+			switch {
+			case s[i] == '\r' && i+1 < len(s) && s[i+1] == '\n':
+				style = EOL_DOS
+			case s[i] == '\r':
+				style = EOL_MAC
+			case s[i] == '\n':
+				style = EOL_UNIX
+			default:
+				continue
+			}
+			records = append(records, s[start:i])
+			i += len(style) - 1
+			start = i + 1
+			// Ties go to the style found first
+			counts[style]++
+			if counts[style] > counts[eol] {
+				eol = style
+			}
+		}
+		records = append(records, s[start:])
+	}
+
+	if opts.DropEmpty && records[len(records)-1] == "" {
+		records = records[:len(records)-1]
+	}
+	return records, eol
+}
+
+// RecordsFromFileContext is like RecordsFromFile, but reads the file as a
+// stream and stops once ctx is done, returning the records read so far and
+// a *ProgressError.
+// With EOL_AUTO, the file is split once read to the end, so that an
+// interrupted read only tells the offset reached.
+func RecordsFromFileContext(ctx context.Context, filename string, eolstyle string) ([]string, error) {
+	f, err := OpenFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	return strings.Split(string(data), eolstyle), nil
+	defer f.Close()
+
+	if eolstyle == EOL_AUTO {
+		data, err := readAllContext(ctx, f)
+		if err != nil {
+			return nil, err
+		}
+		records, _ := splitRecords(data, RecordsOptions{EOL: eolstyle})
+		return records, nil
+	}
+
+	r := bufio.NewReader(f)
+	var records []string
+	var p Progress
+	for {
+		if err := ctx.Err(); err != nil {
+			return records, &ProgressError{Progress: p, Err: err}
+		}
+		record, err := readUntil(r, eolstyle)
+		if err != nil && err != io.EOF {
+			return records, err
+		}
+		// The last record is whatever follows the last terminator, as
+		// with strings.Split
+		records = append(records, strings.TrimSuffix(record, eolstyle))
+		p.Records++
+		p.Lines++
+		p.Offset += int64(len(record))
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+// readAllContext reads r to the end, checking ctx between chunks.
+func readAllContext(ctx context.Context, r io.Reader) (string, error) {
+	var b bytes.Buffer
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", &ProgressError{Progress: Progress{Offset: int64(b.Len())}, Err: err}
+		}
+		if _, err := io.CopyN(&b, r, 64*1024); err == io.EOF {
+			return b.String(), nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
+
+// readUntil reads up to and including sep, or up to the end of the input.
+func readUntil(r *bufio.Reader, sep string) (string, error) {
+	var b []byte
+	for {
+		chunk, err := r.ReadSlice(sep[len(sep)-1])
+		b = append(b, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil || bytes.HasSuffix(b, []byte(sep)) {
+			return string(b), err
+		}
+	}
+}
+
+// LineError records an error found while decoding a given line (record) of
+// the input, optionally naming the field being decoded.
+type LineError struct {
+	Line  int    // One-based line number
+	Field string // Field being decoded, if known
+	Err   error
+}
+
+func (e *LineError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("line %d, %s: %s", e.Line, e.Field, e.Err.Error())
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err.Error())
+}
+
+// Unwrap returns the underlying error.
+func (e *LineError) Unwrap() error {
+	return e.Err
 }
@@ -1,6 +1,15 @@
 package gofixedlength
 
-import "testing"
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
 
 func TestRecordsFromFile(t *testing.T) {
 	s, err := RecordsFromFile("./test.txt", EOL_UNIX)
@@ -15,3 +24,70 @@ func TestRecordsFromFile(t *testing.T) {
 		t.Errorf("Failed to deserialize properly\n")
 	}
 }
+
+func TestRecordsFromFileContext(t *testing.T) {
+	for _, eol := range []string{EOL_UNIX, EOL_DOS, EOL_MAC, EOL_AUTO} {
+		expected, _ := RecordsFromFile("./test.txt", eol)
+		s, err := RecordsFromFileContext(context.Background(), "./test.txt", eol)
+		if err != nil || strings.Join(s, "|") != strings.Join(expected, "|") || len(s) != len(expected) {
+			t.Errorf("%q: got %q (%v), expected %q", eol, s, err, expected)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, eol := range []string{EOL_UNIX, EOL_AUTO} {
+		s, err := RecordsFromFileContext(ctx, "./test.txt", eol)
+		var progressErr *ProgressError
+		if len(s) != 0 || !errors.As(err, &progressErr) || progressErr.Err != context.Canceled {
+			t.Errorf("%q: expected a ProgressError, got %q (%v)", eol, s, err)
+		}
+	}
+}
+
+func TestRecordsFromFileOptions(t *testing.T) {
+	file, err := ioutil.TempFile("", "records")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+	if _, err := file.WriteString("\uFEFF123\r\nABC\r\nDEF\nGHI\r\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		opts    RecordsOptions
+		records string
+		eol     string
+	}{
+		{RecordsOptions{}, "\uFEFF123|ABC|DEF|GHI||", EOL_DOS},
+		{RecordsOptions{DropEmpty: true, StripBOM: true}, "123|ABC|DEF|GHI|", EOL_DOS},
+		{RecordsOptions{EOL: EOL_UNIX, DropEmpty: true}, "\uFEFF123\r|ABC\r|DEF|GHI\r\r", EOL_UNIX},
+	} {
+		s, eol, err := RecordsFromFileOptions(file.Name(), test.opts)
+		if err != nil || strings.Join(s, "|") != test.records || eol != test.eol {
+			t.Errorf("%+v: got %q %q (%v), expected %q %q", test.opts, strings.Join(s, "|"), eol, err, test.records, test.eol)
+		}
+	}
+
+	s, eol, _ := RecordsFromFileOptions("./test.txt", RecordsOptions{DropEmpty: true})
+	if len(s) != 2 || eol != EOL_UNIX {
+		t.Errorf("Expected 2 records ending with EOL_UNIX, got %q %q", s, eol)
+	}
+	s, _ = RecordsFromFile("./test.txt", EOL_AUTO)
+	if len(s) != 3 {
+		t.Errorf("Expected 3 records, got %q", s)
+	}
+}
+
+func TestReadUntil(t *testing.T) {
+	long := strings.Repeat("x\r", 10000)
+	r := bufio.NewReaderSize(strings.NewReader(long+"\r\nABC"), 16)
+	if s, err := readUntil(r, EOL_DOS); err != nil || s != long+"\r\n" {
+		t.Errorf("Expected a long line, got %d bytes (%v)", len(s), err)
+	}
+	if s, err := readUntil(r, EOL_DOS); err != io.EOF || s != "ABC" {
+		t.Errorf("Expected the last line, got %q (%v)", s, err)
+	}
+}
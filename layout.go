@@ -0,0 +1,190 @@
+package gofixedlength
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrLayoutType  = errors.New("Unknown layout field type")
+	ErrLayoutValue = errors.New("Value does not match the layout field type")
+)
+
+// FieldType names the type of a value described by a Layout.
+type FieldType string
+
+const (
+	TypeString FieldType = "string" // string
+	TypeInt    FieldType = "int"    // int
+	TypeUint   FieldType = "uint"   // uint
+	TypeFloat  FieldType = "float"  // float64
+	TypeBool   FieldType = "bool"   // bool
+	TypeTime   FieldType = "time"   // time.Time
+)
+
+var fieldTypes = map[FieldType]reflect.Type{
+	TypeString: reflect.TypeOf(""),
+	TypeInt:    reflect.TypeOf(int(0)),
+	TypeUint:   reflect.TypeOf(uint(0)),
+	TypeFloat:  reflect.TypeOf(float64(0)),
+	TypeBool:   reflect.TypeOf(false),
+	TypeTime:   timeType,
+}
+
+// LayoutField describes a named range of a fixed-length record, like a
+// field tagged `fixed:"Begin-End,Format"`.
+type LayoutField struct {
+	Name   string    `json:"name" yaml:"name"`
+	Begin  int       `json:"begin" yaml:"begin"`
+	End    int       `json:"end" yaml:"end"`
+	Type   FieldType `json:"type,omitempty" yaml:"type,omitempty"`     // TypeString if empty
+	Format string    `json:"format,omitempty" yaml:"format,omitempty"` // Time layout, or number of decimals for floats
+	TZ     string    `json:"tz,omitempty" yaml:"tz,omitempty"`         // Location for times without zone information
+}
+
+// Layout describes a record at runtime, when there is no annotated struct.
+type Layout []LayoutField
+
+// goType returns the Go type of the values of the field.
+func (f LayoutField) goType() (reflect.Type, error) {
+	if f.Type == "" {
+		return fieldTypes[TypeString], nil
+	}
+	t, ok := fieldTypes[f.Type]
+	if !ok {
+		return nil, ErrLayoutType
+	}
+	return t, nil
+}
+
+// options returns the conversion options of the field.
+func (f LayoutField) options() (fieldOptions, error) {
+	opts := fieldOptions{format: f.Format}
+	if f.TZ != "" {
+		loc, err := loadLocation(f.TZ)
+		if err != nil {
+			return opts, err
+		}
+		opts.location = loc
+	}
+	return opts, nil
+}
+
+// Length returns the length of the records described by the layout.
+func (layout Layout) Length() int {
+	var length int
+	for _, f := range layout {
+		if f.End > length {
+			length = f.End
+		}
+	}
+	return length
+}
+
+// UnmarshalToMap decodes a fixed-length record described by layout into a
+// map from field names to values, following the same rules as Unmarshal.
+// Values are stored with the Go type of their FieldType; fields out of the
+// record or which cannot be parsed are left out of the map.
+func UnmarshalToMap(data string, layout Layout) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(layout))
+	for _, f := range layout {
+		t, err := f.goType()
+		if err != nil {
+			return nil, err
+		}
+		opts, err := f.options()
+		if err != nil {
+			return nil, err
+		}
+
+		// Sanity check range before dying miserably
+		if f.Begin < 0 || f.End > len(data) || f.Begin > f.End {
+			continue
+		}
+		s := data[f.Begin:f.End]
+		if t.Kind() == reflect.String {
+			s = strings.TrimRight(s, " ")
+		}
+
+		field := reflect.New(t).Elem()
+		if _, err := decodeValue(field, s, opts); err != nil {
+			// Values which cannot be parsed are skipped
+			continue
+		}
+		values[f.Name] = field.Interface()
+	}
+	return values, nil
+}
+
+// MarshalFromMap encodes the values of a map into a fixed-length record
+// described by layout, following the same rules as Marshal. Values can be
+// given with any Go type convertible to their FieldType without loss, or as
+// text to be parsed; fields missing from the map are left blank, as are
+// booleans.
+func MarshalFromMap(values map[string]interface{}, layout Layout) (string, error) {
+	line := make(Line, layout.Length())
+	for _, f := range layout {
+		t, err := f.goType()
+		if err != nil {
+			return "", err
+		}
+		opts, err := f.options()
+		if err != nil {
+			return "", err
+		}
+		value, ok := values[f.Name]
+		if !ok || value == nil {
+			continue
+		}
+
+		field := reflect.New(t).Elem()
+		v := reflect.ValueOf(value)
+		switch {
+		case v.Type() == t:
+			field.Set(v)
+		case isNumberKind(v.Kind()) && isNumberKind(t.Kind()):
+			converted, err := convertNumber(v, t)
+			if err != nil {
+				return "", err
+			}
+			field.Set(converted)
+		case v.Kind() == reflect.String:
+			if _, err := decodeValue(field, v.String(), opts); err != nil {
+				return "", err
+			}
+		default:
+			return "", ErrLayoutValue
+		}
+
+		if _, err := writeFixed(line, field, opts, f.Begin, f.End); err != nil {
+			return "", err
+		}
+	}
+	for i, r := range line {
+		if r == 0 {
+			line[i] = ' '
+		}
+	}
+	return line.String(), nil
+}
+
+// convertNumber converts a number to type t, returning ErrLayoutValue if
+// its value does not survive the conversion, like 7.9 given for an int.
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	converted := v.Convert(t)
+	if converted.Convert(v.Type()).Interface() != v.Interface() || isNegative(converted) != isNegative(v) {
+		return converted, ErrLayoutValue
+	}
+	return converted, nil
+}
+
+func isNegative(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() < 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() < 0
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package gofixedlength
+
+import (
+	"testing"
+	"time"
+)
+
+var layoutTest = Layout{
+	{Name: "Code", Begin: 0, End: 4},
+	{Name: "Date", Begin: 4, End: 12, Type: TypeTime, Format: "20060102"},
+	{Name: "Count", Begin: 12, End: 17, Type: TypeInt},
+	{Name: "Amount", Begin: 17, End: 25, Type: TypeFloat, Format: "2"},
+	{Name: "Active", Begin: 25, End: 26, Type: TypeBool},
+}
+
+func TestUnmarshalToMap(t *testing.T) {
+	values, err := UnmarshalToMap("AB  2015011400042-0012.5011", layoutTest)
+	if err != nil {
+		t.Fatalf("UnmarshalToMap failed: %v", err)
+	}
+	if values["Code"] != "AB" || values["Count"] != 42 || values["Amount"] != -12.5 || values["Active"] != true {
+		t.Errorf("Parsed as %v", values)
+	}
+	if date, ok := values["Date"].(time.Time); !ok || !date.Equal(time.Date(2015, 1, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Date parsed as %v", values["Date"])
+	}
+
+	values, err = UnmarshalToMap("AB  2015011400x42", layoutTest)
+	if _, ok := values["Count"]; err != nil || ok || len(values) != 2 {
+		t.Errorf("Expected invalid and missing values to be skipped, got %v (%v)", values, err)
+	}
+
+	if _, err := UnmarshalToMap("", Layout{{Name: "X", Type: "decimal"}}); err != ErrLayoutType {
+		t.Errorf("Expected ErrLayoutType, got %v", err)
+	}
+}
+
+func TestMarshalFromMap(t *testing.T) {
+	line, err := MarshalFromMap(map[string]interface{}{
+		"Code":   "AB",
+		"Date":   time.Date(2015, 1, 14, 0, 0, 0, 0, time.UTC),
+		"Count":  int64(42),
+		"Amount": "-12.5",
+		"Active": true,
+	}, layoutTest)
+	if err != nil || line != "AB  2015011400042-0012.50 " {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+
+	line, err = MarshalFromMap(map[string]interface{}{"Count": 7}, layoutTest)
+	if err != nil || line != "            00007         " {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+
+	if _, err := MarshalFromMap(map[string]interface{}{"Active": 1}, layoutTest); err != ErrLayoutValue {
+		t.Errorf("Expected ErrLayoutValue, got %v", err)
+	}
+	for _, value := range []interface{}{7.9, -1, 1e30} {
+		if _, err := MarshalFromMap(map[string]interface{}{"Count": value}, Layout{{Name: "Count", End: 5, Type: TypeUint}}); err != ErrLayoutValue {
+			t.Errorf("Expected ErrLayoutValue for %v, got %v", value, err)
+		}
+	}
+	if line, err := MarshalFromMap(map[string]interface{}{"Count": 7.0}, layoutTest); err != nil || line[12:17] != "00007" {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+}
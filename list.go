@@ -0,0 +1,153 @@
+package gofixedlength
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrListItem = errors.New("Unsupported list item type")
+
+// listOptions describes how a slice field is split into its items: either
+// by a separator or into items of a fixed width.
+type listOptions struct {
+	sep     string // From the `csvsplit` or `fixedsplit` tag
+	width   int    // From the `listwidth` tag
+	itemSep string // From the `itemsplit` tag, for delimited struct items
+	trim    bool   // Trim the spaces padding text items, as in fixed fields
+}
+
+// newListOptions reads the options of a slice field, given the name of the
+// tag holding its separator. It returns false if the field has neither a
+// separator nor an item width.
+func newListOptions(tag reflect.StructTag, sepTag string) (listOptions, bool) {
+	l := listOptions{sep: tag.Get(sepTag), itemSep: tag.Get("itemsplit")}
+	l.width, _ = strconv.Atoi(tag.Get("listwidth"))
+	return l, l.sep != "" || l.width > 0
+}
+
+// split splits a list into its items. Delimited items can be quoted as in
+// RFC 4180, while blank items at the end of a width-based list are taken as
+// padding and dropped.
+func (l listOptions) split(s string) ([]string, error) {
+	if l.width <= 0 {
+		if s == "" {
+			return nil, nil
+		}
+		return splitCsv(s, CsvOptions{Separator: l.sep})
+	}
+
+	var items []string
+	runes := []rune(s)
+	for len(runes) > 0 {
+		n := l.width
+		if n > len(runes) {
+			n = len(runes)
+		}
+		items = append(items, string(runes[:n]))
+		runes = runes[n:]
+	}
+	for len(items) > 0 && strings.TrimSpace(items[len(items)-1]) == "" {
+		items = items[:len(items)-1]
+	}
+	return items, nil
+}
+
+// decodeList splits s and stores its items into the slice field. Items are
+// converted like single fields, while struct items are parsed with
+// Unmarshal, or with UnmarshalCsv when there is an item separator. The
+// field is left untouched if an item cannot be parsed.
+func decodeList(field reflect.Value, s string, l listOptions, opts fieldOptions) error {
+	items, err := l.split(s)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	list := reflect.MakeSlice(field.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := decodeItem(list.Index(i), item, l, opts); err != nil {
+			return err
+		}
+	}
+	field.Set(list)
+	return nil
+}
+
+func decodeItem(elem reflect.Value, s string, l listOptions, opts fieldOptions) error {
+	if l.trim && elem.Kind() == reflect.String {
+		s = strings.TrimRight(s, " ")
+	}
+	if handled, err := decodeValue(elem, s, opts); handled {
+		return err
+	}
+
+	if elem.Kind() == reflect.Ptr && elem.Type().Elem().Kind() == reflect.Struct {
+		elem.Set(reflect.New(elem.Type().Elem()))
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return ErrListItem
+	}
+	var err error
+	if l.itemSep != "" {
+		err = UnmarshalCsv(s, l.itemSep, elem.Addr().Interface())
+	} else {
+		err = Unmarshal(s, elem.Addr().Interface())
+	}
+	if _, ok := err.(ValidationErrors); ok {
+		// Items are validated with the struct holding the list
+		return nil
+	}
+	return err
+}
+
+// encodeList formats the items of the slice field and joins them. Items of
+// a width-based list are padded as fixed fields of that width, and nil
+// pointers are left empty.
+func encodeList(field reflect.Value, l listOptions, opts fieldOptions) (string, error) {
+	items := make([]string, field.Len())
+	for i := range items {
+		elem := field.Index(i)
+
+		var s string
+		var handled bool
+		var err error
+		if l.width > 0 {
+			s, handled, err = encodeFixed(elem, opts, l.width)
+		} else {
+			s, handled = encodeValue(elem, opts)
+		}
+		if !handled {
+			switch sub := reflect.Indirect(elem); {
+			case elem.Kind() == reflect.Ptr && elem.IsNil():
+			case sub.Kind() != reflect.Struct:
+				return "", ErrListItem
+			case l.itemSep != "":
+				s, err = MarshalCsv(sub.Interface(), l.itemSep)
+			default:
+				s, err = Marshal(sub.Interface())
+			}
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if l.width > 0 {
+			if utf8.RuneCountInString(s) > l.width {
+				return "", ErrTextTooLongForRange
+			}
+			s = padRight(s, l.width)
+		}
+		items[i] = s
+	}
+
+	if l.width > 0 {
+		return strings.Join(items, ""), nil
+	}
+	return joinCsv(items, CsvOptions{Separator: l.sep}), nil
+}
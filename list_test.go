@@ -0,0 +1,78 @@
+package gofixedlength
+
+import "testing"
+
+type listItem struct {
+	Code   string `fixed:"0-2" csv:"0"`
+	Amount int    `fixed:"2-5" csv:"1"`
+}
+
+type fixedListTest struct {
+	Codes   []string    `fixed:"0-12" fixedsplit:";"`
+	Numbers []int       `fixed:"12-28" listwidth:"4"`
+	Items   []listItem  `fixed:"28-43" listwidth:"5"`
+	Ptrs    []*listItem `fixed:"43-53" fixedsplit:"|" itemsplit:"~"`
+}
+
+func TestFixedLists(t *testing.T) {
+	data := "A;B;C       00010002003     AB001CD002     AB~1|CD~2 "
+	var out fixedListTest
+	if err := Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if len(out.Codes) != 3 || out.Codes[2] != "C" {
+		t.Errorf("Codes parsed as %q", out.Codes)
+	}
+	if len(out.Numbers) != 3 || out.Numbers[0] != 1 || out.Numbers[2] != 3 {
+		t.Errorf("Numbers parsed as %v", out.Numbers)
+	}
+	if len(out.Items) != 2 || out.Items[1].Code != "CD" || out.Items[1].Amount != 2 {
+		t.Errorf("Items parsed as %+v", out.Items)
+	}
+	if len(out.Ptrs) != 2 || out.Ptrs[0].Code != "AB" || out.Ptrs[1].Amount != 2 {
+		t.Errorf("Ptrs parsed as %+v", out.Ptrs)
+	}
+
+	line, err := Marshal(out)
+	if err != nil || line != "A;B;C       000100020003    AB001CD002     AB~1|CD~2 " {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+
+	out.Numbers = []int{12345}
+	if _, err := Marshal(out); err != ErrTextTooLongForRange {
+		t.Errorf("Expected ErrTextTooLongForRange, got %v", err)
+	}
+}
+
+type csvListTest struct {
+	Tags   []string   `csv:"0" csvsplit:";"`
+	Values []float64  `csv:"1" csvsplit:";"`
+	Items  []listItem `csv:"2" listwidth:"5"`
+}
+
+func TestCsvLists(t *testing.T) {
+	var out csvListTest
+	data := `"a;""b;c""",1.5;2,AB001CD002`
+	if err := UnmarshalCsv(data, ",", &out); err != nil {
+		t.Fatalf("UnmarshalCsv failed: %v", err)
+	}
+	if len(out.Tags) != 2 || out.Tags[1] != "b;c" {
+		t.Errorf("Tags parsed as %q", out.Tags)
+	}
+	if len(out.Values) != 2 || out.Values[0] != 1.5 || out.Values[1] != 2 {
+		t.Errorf("Values parsed as %v", out.Values)
+	}
+	if len(out.Items) != 2 || out.Items[0].Amount != 1 {
+		t.Errorf("Items parsed as %+v", out.Items)
+	}
+
+	line, err := MarshalCsv(out, ",")
+	if err != nil || line != data {
+		t.Errorf("Marshalled as '%s' (%v)", line, err)
+	}
+
+	var empty csvListTest
+	if err := UnmarshalCsv(",,", ",", &empty); err != nil || empty.Tags != nil {
+		t.Errorf("Empty lists parsed as %q (%v)", empty.Tags, err)
+	}
+}
@@ -0,0 +1,157 @@
+package gofixedlength
+
+import (
+	"context"
+	"io"
+	"runtime"
+)
+
+// DecodeResult is a record decoded by DecodeParallel.
+type DecodeResult struct {
+	Line  int         // One-based line number of the record
+	Value interface{} // Value returned by newValue, holding the record
+	Err   error       // *LineError if the record could not be decoded
+}
+
+// ParallelOptions tunes DecodeParallel. Zero values select the defaults.
+type ParallelOptions struct {
+	Workers   int // Decoding goroutines, GOMAXPROCS by default
+	ChunkSize int // Records handed to a worker at once, 1000 by default
+	MaxChunks int // Chunks read ahead of the consumer, 2 × Workers by default
+}
+
+func (o ParallelOptions) withDefaults() ParallelOptions {
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = 1000
+	}
+	if o.MaxChunks <= 0 {
+		o.MaxChunks = 2 * o.Workers
+	}
+	return o
+}
+
+// decodeChunk is a run of consecutive records, decoded by a single worker.
+type decodeChunk struct {
+	records []string
+	lines   []int
+	results []DecodeResult
+	done    chan struct{} // Closed once results are set
+}
+
+// DecodeParallel reads fixed-length records, one per line as for
+// FixedDecoder, and unmarshals them on several goroutines into values
+// returned by newValue, which must be pointers to fresh values.
+//
+// Results are sent in input order; records which cannot be decoded give
+// a result with a *LineError and decoding goes on. An error reading r is
+// sent as the last result. At most MaxChunks chunks of records are held in
+// memory at once.
+//
+// The channel is closed at the end of the input, or as soon as ctx is
+// done, leaving ctx.Err() to tell why. Consumers stopping early must cancel
+// ctx to release the goroutines.
+func DecodeParallel(ctx context.Context, r io.Reader, newValue func() interface{}, opts ParallelOptions) <-chan DecodeResult {
+	opts = opts.withDefaults()
+	out := make(chan DecodeResult, opts.ChunkSize)
+	work := make(chan *decodeChunk)
+	// Chunks in input order; its capacity bounds the records in memory
+	order := make(chan *decodeChunk, opts.MaxChunks)
+
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			for c := range work {
+				c.decode(ctx, newValue)
+			}
+		}()
+	}
+	go func() {
+		defer close(order)
+		defer close(work)
+		readChunks(ctx, r, opts.ChunkSize, work, order)
+	}()
+	go func() {
+		defer close(out)
+		for c := range order {
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+				return
+			}
+			for _, res := range c.results {
+				// A ready send may win the select over a done context
+				if ctx.Err() != nil {
+					return
+				}
+				select {
+				case out <- res:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// readChunks splits the records of r into chunks of size records, queued
+// in order before being handed to the workers.
+func readChunks(ctx context.Context, r io.Reader, size int, work, order chan<- *decodeChunk) {
+	d := NewFixedDecoder(r)
+	for {
+		c := &decodeChunk{done: make(chan struct{})}
+		var err error
+		for len(c.records) < size {
+			var record []byte
+			if record, err = d.ReadRecord(); err != nil {
+				break
+			}
+			c.records = append(c.records, string(record))
+			c.lines = append(c.lines, d.Line())
+		}
+		if len(c.records) > 0 {
+			select {
+			case order <- c:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case work <- c:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			c = &decodeChunk{results: []DecodeResult{{Line: d.Line() + 1, Err: err}}, done: make(chan struct{})}
+			close(c.done)
+			select {
+			case order <- c:
+			case <-ctx.Done():
+			}
+			return
+		}
+	}
+}
+
+// decode unmarshals the records of the chunk, giving up if ctx is done:
+// results then only hold the records decoded.
+func (c *decodeChunk) decode(ctx context.Context, newValue func() interface{}) {
+	defer close(c.done)
+	c.results = make([]DecodeResult, len(c.records))
+	for i, record := range c.records {
+		if ctx.Err() != nil {
+			c.results = c.results[:i]
+			return
+		}
+		v := newValue()
+		c.results[i] = DecodeResult{Line: c.lines[i], Value: v}
+		if err := Unmarshal(record, v); err != nil {
+			c.results[i].Err = &LineError{Line: c.lines[i], Err: err}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gofixedlength
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type parallelTest struct {
+	Code  string `fixed:"0-1" validate:"oneof=A B"`
+	Index int    `fixed:"1-7"`
+}
+
+func parallelData(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		code := "A"
+		if i%100 == 99 {
+			code = "X"
+		}
+		fmt.Fprintf(&b, "%s%06d\r\n", code, i)
+	}
+	return b.String()
+}
+
+func TestDecodeParallel(t *testing.T) {
+	newValue := func() interface{} { return new(parallelTest) }
+	opts := ParallelOptions{Workers: 4, ChunkSize: 7, MaxChunks: 3}
+	n := 0
+	for res := range DecodeParallel(context.Background(), strings.NewReader(parallelData(1000)), newValue, opts) {
+		v := res.Value.(*parallelTest)
+		if res.Line != n+1 || v.Index != n {
+			t.Fatalf("Expected record %d at line %d, got %d at line %d", n, n+1, v.Index, res.Line)
+		}
+		var lineErr *LineError
+		if bad := n%100 == 99; bad != errors.As(res.Err, &lineErr) {
+			t.Errorf("Record %d: unexpected error %v", n, res.Err)
+		}
+		n++
+	}
+	if n != 1000 {
+		t.Errorf("Expected 1000 records, got %d", n)
+	}
+}
+
+type failingReader struct {
+	r io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func TestDecodeParallelReadError(t *testing.T) {
+	newValue := func() interface{} { return new(parallelTest) }
+	var results []DecodeResult
+	for res := range DecodeParallel(context.Background(), failingReader{strings.NewReader(parallelData(10))}, newValue, ParallelOptions{ChunkSize: 3}) {
+		results = append(results, res)
+	}
+	if len(results) != 11 {
+		t.Fatalf("Expected 11 results, got %d", len(results))
+	}
+	if last := results[10]; last.Err != io.ErrUnexpectedEOF || last.Line != 11 {
+		t.Errorf("Expected read error at line 11, got %v at line %d", last.Err, last.Line)
+	}
+}
+
+func TestDecodeParallelCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newValue := func() interface{} { return new(parallelTest) }
+	results := DecodeParallel(ctx, strings.NewReader(parallelData(100000)), newValue, ParallelOptions{ChunkSize: 10})
+	n := 0
+	for range results {
+		n++
+		if n == 50 {
+			cancel()
+		}
+	}
+	if n >= 100000 {
+		t.Errorf("Expected decoding to stop, got %d records", n)
+	}
+	if ctx.Err() != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", ctx.Err())
+	}
+}
+
+func TestDecodeParallelCancelResults(t *testing.T) {
+	newValue := func() interface{} { return new(parallelTest) }
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		results := DecodeParallel(ctx, strings.NewReader(parallelData(20000)), newValue, ParallelOptions{Workers: 8, ChunkSize: 50, MaxChunks: 16})
+		n := 0
+		for res := range results {
+			if res.Value == nil || res.Line == 0 {
+				t.Fatalf("Got an empty result after cancellation: %+v", res)
+			}
+			if n++; n == 10+i*50 {
+				cancel()
+			}
+		}
+		cancel()
+	}
+}
@@ -1,13 +1,18 @@
 package gofixedlength
 
 import (
-	"fmt"
 	"reflect"
-	"strconv"
 	"strings"
-	"time"
+
+	"github.com/qrawl/gofixedlength/internal/fixedtag"
 )
 
+// FixedUnmarshaler is implemented by types decoding fixed-length records
+// without reflection, like the methods written by gofixedgen.
+type FixedUnmarshaler interface {
+	UnmarshalFixed(data string) error
+}
+
 // Unmarshal unmarshals string data into an annotated interface. This should
 // resemble:
 //
@@ -21,8 +26,22 @@ import (
 //	err := Unmarshal("20150202well   00012.1864", &out)
 //
 // Offsets are zero based.
+// Embedded structs with a range are parsed from that range, with offsets
+// relative to it. Untagged fields are left out.
+// With a `fixedsplit:","` tag, the range holds a delimited record instead,
+// parsed with UnmarshalCsv.
+// Slices hold lists, split by their `fixedsplit` separator, as in
+// `fixed:"0-20" fixedsplit:";"`, or into items of their `listwidth`, as in
+// `fixed:"0-16" listwidth:"4"`. Struct items are parsed with Unmarshal, or
+// with UnmarshalCsv using their `itemsplit` separator.
+// Values which cannot be parsed are skipped.
+// Decoded values are checked against their `validate` tags (see Validate).
+// Types implementing FixedUnmarshaler decode themselves instead.
 func Unmarshal(data string, v interface{}) error {
 	// debugStruct(v) // Debug code
+	if u, ok := v.(FixedUnmarshaler); ok {
+		return u.UnmarshalFixed(data)
+	}
 	var val reflect.Value
 	if reflect.TypeOf(v).Name() != "" {
 		val = reflect.ValueOf(v)
@@ -33,30 +52,16 @@ func Unmarshal(data string, v interface{}) error {
 	// fmt.Printf("Found %d fields\n", val.NumField()) // Debug code
 	for i := 0; i < val.NumField(); i++ {
 		typeField := val.Type().Field(i)
-		tag := typeField.Tag
-
-		cArguments := strings.SplitN(tag.Get("fixed"), ",", 2)
-		var cFormat string
-		if len(cArguments) > 1 {
-			cFormat = cArguments[1]
-		}
-		cRange := cArguments[0]
-		cBookend := strings.Split(cRange, "-")
+		field := val.Field(i)
 
-		if len(cBookend) != 2 {
+		b, e, cFormat, ok := fixedTag(typeField.Tag)
+		if !ok {
 			// If we don't have two values, skip
-			// fmt.Println("Two tag values not found") // Debug code
 			continue
 		}
 
-		b, _ := strconv.Atoi(cBookend[0])
-		e, _ := strconv.Atoi(cBookend[1])
-
-		//b--
-		//e--
-
 		// Sanity check range before dying miserably
-		if b < 0 || e > len(data) {
+		if b < 0 || e > len(data) || b > e {
 			// fmt.Printf("Failed sanity check for b = %d, e = %d, len(data) = %d\n", b, e, len(data)) // Debug code
 			continue
 		}
@@ -65,107 +70,56 @@ func Unmarshal(data string, v interface{}) error {
 
 		// fmt.Printf("Field found of type %s\n", typeField.Type.Kind()) // Debug code
 
+		opts, err := newFieldOptions(typeField, cFormat, nil)
+		if err != nil {
+			return err
+		}
+		if typeField.Type.Kind() == reflect.String {
+			s = strings.TrimRight(s, " ")
+		}
+		if handled, _ := decodeValue(field, s, opts); handled {
+			// Values which cannot be parsed are skipped
+			continue
+		}
+
 		switch typeField.Type.Kind() {
-		case reflect.Bool:
-			v, err := strconv.ParseBool(s)
-			if err != nil {
-				// fmt.Println(err.Error()) // Debug code
-				continue
-			}
-			val.Field(i).SetBool(v)
-			break
-		case reflect.Float32:
-			if DECIMAL_COMMA {
-				s = strings.Replace(s, ",", ".", 1)
-			}
-			v, err := strconv.ParseFloat(s, 32)
-			if err != nil {
-				// fmt.Println(err.Error()) // Debug code
-				continue
-			}
-			val.Field(i).SetFloat(v)
-			break
-		case reflect.Float64:
-			if DECIMAL_COMMA {
-				s = strings.Replace(s, ",", ".", 1)
-			}
-			v, err := strconv.ParseFloat(s, 64)
-			if err != nil {
-				// fmt.Println(err.Error()) // Debug code
-				continue
-			}
-			val.Field(i).SetFloat(v)
-			break
-		case reflect.String:
-			// fmt.Printf("Found string value '%s'\n", s) // Debug code
-			val.Field(i).SetString(strings.TrimRight(s, " "))
-			break
-		case reflect.Int8:
-			// fmt.Printf("Found value '%s'\n", s) // Debug code
-			v, err := strconv.ParseInt(s, 10, 8)
-			if err != nil {
-				// fmt.Println(err.Error()) // Debug code
-				continue
-			}
-			val.Field(i).SetInt(v)
-			break
-		case reflect.Int32:
-			// fmt.Printf("Found value '%s'\n", s) // Debug code
-			v, err := strconv.ParseInt(s, 10, 32)
-			if err != nil {
-				fmt.Println(err.Error())
-				continue
+		case reflect.Ptr, reflect.Struct:
+			// fmt.Printf("Found ptr/str value '%s'\n", s) // Debug code
+
+			// Handle embedded objects by recursively parsing
+			// the object with the range we passed.
+			if typeField.Type.Kind() == reflect.Struct {
+				field = field.Addr()
+			} else if field.IsNil() {
+				// Initialize pointer to avoid panic
+				field.Set(reflect.New(field.Type().Elem()))
 			}
-			val.Field(i).SetInt(v)
-			break
-		case reflect.Int, reflect.Int64:
-			// fmt.Printf("Found value '%s'\n", s) // Debug code
-			v, err := strconv.ParseInt(s, 10, 64)
-			if err != nil {
-				// fmt.Println(err.Error()) // Debug code
-				continue
+			var err error
+			if cSep := typeField.Tag.Get("fixedsplit"); cSep != "" {
+				// Delimited object inside a fixed field
+				err = UnmarshalCsv(strings.TrimRight(s, " "), cSep, field.Interface())
+			} else {
+				err = Unmarshal(s, field.Interface())
 			}
-			val.Field(i).SetInt(v)
-			break
-		case reflect.Uint:
-			// fmt.Printf("Found uint value '%s'\n", s) // Debug code
-			v, err := strconv.ParseUint(s, 10, 64)
 			if err != nil {
 				// fmt.Println(err.Error()) // Debug code
-				continue
 			}
-			val.Field(i).SetUint(v)
-			break
-		case reflect.Ptr, reflect.Struct:
-			// Code for parsing time.Time object.
-			// It probably doesn't work with pointers.
-			if typeField.Type == reflect.TypeOf(time.Time{}) {
-				timeObject, err := time.Parse(cFormat, s)
-				if err != nil {
-					// fmt.Println(err.Error()) // Debug code
-					continue
-				}
-				// How to store this time.Time object?
-				val.Field(i).Set(reflect.ValueOf(timeObject))
-			} else {
-				// fmt.Printf("Found ptr/str value '%s'\n", s) // Debug code
-
-				// Handle embedded objects by recursively parsing
-				// the object with the range we passed.
-				if val.Field(i).IsNil() {
-					// Initialize pointer to avoid panic
-					val.Field(i).Set(reflect.New(val.Field(i).Type().Elem()))
-				}
-				err := Unmarshal(s, val.Field(i).Interface())
+		case reflect.Slice:
+			if lOpts, ok := newListOptions(typeField.Tag, "fixedsplit"); ok {
+				lOpts.trim = true
+				err := decodeList(field, strings.TrimRight(s, " "), lOpts, opts)
 				if err != nil {
 					// fmt.Println(err.Error()) // Debug code
 				}
 			}
-			break
 		default:
 			// fmt.Println("Found unknown value '%s'", s) // Debug code
-			break
 		}
 	}
-	return nil
+	return validateStruct(val, false)
+}
+
+// fixedTag parses a `fixed` tag into its range and the format following it.
+func fixedTag(tag reflect.StructTag) (b, e int, format string, ok bool) {
+	return fixedtag.Parse(tag)
 }
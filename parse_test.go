@@ -53,7 +53,7 @@ func TestLayeredParsing(t *testing.T) {
 		t.Errorf("Failed to parse after embedded struct/ptr\n")
 	}
 	if expectedTime, err := time.Parse("2006-01-02", "2015-01-14"); err != nil || out.DateField != expectedTime {
-		t.Errorf("Failed to parse date (%d)\n", out.DateField)
+		t.Errorf("Failed to parse date (%v)\n", out.DateField)
 	}
 }
 
@@ -67,3 +67,70 @@ func TestBasicParsingWithComma(t *testing.T) {
 		t.Errorf("FloatA parsed as '%v'", out.FloatA)
 	}
 }
+
+type untrimmedParseTest struct {
+	Number int     `fixed:"0-5"`
+	Flag   bool    `fixed:"5-10"`
+	Ratio  float64 `fixed:"10-15"`
+	Common dateParseTest
+}
+
+func TestParsingLeavesSpacesAndUntagged(t *testing.T) {
+	var out untrimmedParseTest
+	if err := Unmarshal("  123true 1.5  ", &out); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	// Numbers padded with spaces cannot be parsed and are skipped
+	if out.Number != 0 || out.Flag || out.Ratio != 0 {
+		t.Errorf("Parsed padded values as %+v", out)
+	}
+	// Untagged structs are left alone
+	if out.Common.StringAfter != "" || !out.Common.DateField.IsZero() {
+		t.Errorf("Parsed untagged struct as %+v", out.Common)
+	}
+}
+
+type parityTest struct {
+	Small  int16      `fixed:"0-4" csv:"0"`
+	Byte   uint8      `fixed:"4-7" csv:"1"`
+	Large  uint64     `fixed:"7-12" csv:"2"`
+	Ratio  float32    `fixed:"12-17,2" csv:"3,2"`
+	Flag   bool       `fixed:"17-18" csv:"4"`
+	Date   *time.Time `fixed:"18-26,20060102" csv:"5,20060102"`
+	Text   string     `fixed:"26-30" csv:"6"`
+	Nested parityNest `fixed:"30-34" csv:"7" csvsplit:"/"`
+}
+
+type parityNest struct {
+	A int `fixed:"0-2" csv:"0"`
+	B int `fixed:"2-4" csv:"1"`
+}
+
+func TestFixedCsvParity(t *testing.T) {
+	date := time.Date(2015, 1, 14, 0, 0, 0, 0, time.UTC)
+	expected := parityTest{-12, 255, 70000, 1.25, true, &date, "ab", parityNest{1, 2}}
+
+	var fixed, csv parityTest
+	if err := Unmarshal("-0122557000001.25120150114ab  0102", &fixed); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := UnmarshalCsv("-12,255,70000,1.25,1,20150114,ab,1/2", ",", &csv); err != nil {
+		t.Fatalf("UnmarshalCsv failed: %v", err)
+	}
+	for _, out := range []parityTest{fixed, csv} {
+		if out.Small != expected.Small || out.Byte != expected.Byte || out.Large != expected.Large ||
+			out.Ratio != expected.Ratio || out.Flag != expected.Flag || out.Date == nil || !out.Date.Equal(date) ||
+			out.Text != expected.Text || out.Nested != expected.Nested {
+			t.Errorf("Parsed as %+v", out)
+		}
+	}
+
+	s, err := Marshal(expected)
+	if err != nil || s != "-0122557000001.25 20150114ab  0102" {
+		t.Errorf("Marshalled as '%s' (%v)", s, err)
+	}
+	s, err = MarshalCsv(expected, ",")
+	if err != nil || s != "-12,255,70000,1.25,true,20150114,ab,1/2" {
+		t.Errorf("Marshalled as '%s' (%v)", s, err)
+	}
+}
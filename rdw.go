@@ -0,0 +1,250 @@
+package gofixedlength
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	ErrInvalidRDW    = errors.New("Invalid record descriptor word")
+	ErrInvalidBDW    = errors.New("Invalid block descriptor word")
+	ErrRecordTooLong = errors.New("Record is too long for its descriptor word or block")
+)
+
+// maxRdwLength is the largest record, descriptor word included, of a
+// variable-length dataset.
+const maxRdwLength = 32760
+
+// Segment kinds, from the third byte of a record descriptor word.
+const (
+	segmentComplete = 0
+	segmentFirst    = 1
+	segmentLast     = 2
+	segmentMiddle   = 3
+)
+
+// RdwDecoder reads variable-length records, as found in mainframe V, VB
+// and VBS datasets transferred in binary mode. Every record starts with a
+// 4-byte record descriptor word (RDW) holding its big-endian length,
+// including the RDW itself. In blocked datasets, records are grouped into
+// blocks starting with a block descriptor word (BDW) in the same format,
+// or with the high bit set for extended 31-bit lengths. Spanned records
+// are gathered from their segments.
+//
+// Payloads are decoded as they are: text in EBCDIC must be translated
+// first. Fields beyond the end of shorter records are left untouched.
+type RdwDecoder struct {
+	r        *bufio.Reader
+	blocked  bool
+	block    int    // Bytes left in the current block
+	seg      []byte // Current segment
+	buf      []byte // Current record
+	progress Progress
+}
+
+// NewRdwDecoder returns a decoder reading from r, which holds blocks with
+// BDWs if blocked is set.
+func NewRdwDecoder(r io.Reader, blocked bool) *RdwDecoder {
+	return &RdwDecoder{r: bufio.NewReader(r), blocked: blocked}
+}
+
+// Decode reads the next record and unmarshals it into v with
+// UnmarshalBytes. It returns io.EOF when there are no more records;
+// decoding errors are *LineError values, numbering records from one.
+func (d *RdwDecoder) Decode(v interface{}) error {
+	record, err := d.ReadRecord()
+	if err != nil {
+		return err
+	}
+	if err := UnmarshalBytes(record, v); err != nil {
+		return &LineError{Line: d.progress.Records, Err: err}
+	}
+	return nil
+}
+
+// DecodeContext is like Decode, but returns a *ProgressError instead of
+// reading further once ctx is done. A read blocked on the underlying
+// reader is not interrupted.
+func (d *RdwDecoder) DecodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return &ProgressError{Progress: d.progress, Err: err}
+	}
+	return d.Decode(v)
+}
+
+// ReadRecord returns the payload of the next record, without its RDW. The
+// slice is only valid until the next call.
+func (d *RdwDecoder) ReadRecord() ([]byte, error) {
+	d.buf = d.buf[:0]
+	spanned := false
+	for {
+		seg, kind, err := d.readSegment()
+		if err == io.EOF && spanned {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if spanned != (kind == segmentLast || kind == segmentMiddle) {
+			return nil, ErrInvalidRDW
+		}
+		if kind == segmentComplete && !spanned {
+			d.progress.Records++
+			d.progress.Lines++
+			return seg, nil
+		}
+		d.buf = append(d.buf, seg...)
+		spanned = true
+		if kind == segmentLast {
+			d.progress.Records++
+			d.progress.Lines++
+			return d.buf, nil
+		}
+	}
+}
+
+// Records returns the number of records read so far.
+func (d *RdwDecoder) Records() int {
+	return d.progress.Records
+}
+
+// Progress returns the records read so far, each counting as a line, and
+// the offset of the next descriptor word in the input.
+func (d *RdwDecoder) Progress() Progress {
+	return d.progress
+}
+
+// readSegment reads a record or a segment of a spanned record, along with
+// its kind.
+func (d *RdwDecoder) readSegment() ([]byte, byte, error) {
+	for d.blocked && d.block == 0 {
+		var bdw [4]byte
+		if _, err := io.ReadFull(d.r, bdw[:]); err != nil {
+			return nil, 0, err
+		}
+		d.progress.Offset += 4
+		n := int(binary.BigEndian.Uint16(bdw[:2]))
+		if bdw[0]&0x80 != 0 {
+			// Extended BDW
+			n = int(binary.BigEndian.Uint32(bdw[:]) &^ (1 << 31))
+		} else if bdw[2] != 0 || bdw[3] != 0 {
+			return nil, 0, ErrInvalidBDW
+		}
+		if n < 4 {
+			return nil, 0, ErrInvalidBDW
+		}
+		d.block = n - 4
+	}
+
+	var rdw [4]byte
+	if _, err := io.ReadFull(d.r, rdw[:]); err != nil {
+		if err == io.EOF && d.blocked {
+			// The block said there was more
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	n := int(binary.BigEndian.Uint16(rdw[:2]))
+	if n < 4 || rdw[2] > segmentMiddle || rdw[3] != 0 || d.blocked && n > d.block {
+		return nil, 0, ErrInvalidRDW
+	}
+	if cap(d.seg) < n-4 {
+		d.seg = make([]byte, n-4)
+	}
+	d.seg = d.seg[:n-4]
+	if _, err := io.ReadFull(d.r, d.seg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, 0, err
+	}
+	if d.blocked {
+		d.block -= n
+	}
+	d.progress.Offset += int64(n)
+	return d.seg, rdw[2], nil
+}
+
+// RdwEncoder writes variable-length records with RDWs, grouped into
+// blocks with BDWs if a block size is given. Records are not spanned.
+type RdwEncoder struct {
+	w         *bufio.Writer
+	blockSize int
+	block     []byte // Pending records of the current block
+	records   int
+}
+
+// NewRdwEncoder returns an encoder writing to w, in blocks of at most
+// blockSize bytes, BDW included, or unblocked if blockSize is 0.
+func NewRdwEncoder(w io.Writer, blockSize int) *RdwEncoder {
+	return &RdwEncoder{w: bufio.NewWriter(w), blockSize: blockSize}
+}
+
+// Encode marshals v and writes it as a record. Marshalling errors are
+// *LineError values, numbering records from one.
+func (e *RdwEncoder) Encode(v interface{}) error {
+	record, err := Marshal(v)
+	if err != nil {
+		return &LineError{Line: e.records + 1, Err: err}
+	}
+	return e.WriteRecord([]byte(record))
+}
+
+// WriteRecord writes a record, prefixed with its RDW.
+func (e *RdwEncoder) WriteRecord(record []byte) error {
+	n := len(record) + 4
+	if n > maxRdwLength || e.blockSize > 0 && n > e.blockSize-4 {
+		return ErrRecordTooLong
+	}
+	var rdw [4]byte
+	binary.BigEndian.PutUint16(rdw[:2], uint16(n))
+
+	if e.blockSize == 0 {
+		if _, err := e.w.Write(rdw[:]); err != nil {
+			return err
+		}
+		if _, err := e.w.Write(record); err != nil {
+			return err
+		}
+	} else {
+		if 4+len(e.block)+n > e.blockSize {
+			if err := e.writeBlock(); err != nil {
+				return err
+			}
+		}
+		e.block = append(append(e.block, rdw[:]...), record...)
+	}
+	e.records++
+	return nil
+}
+
+// Flush writes the pending block and any buffered data to the underlying
+// writer.
+func (e *RdwEncoder) Flush() error {
+	if err := e.writeBlock(); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+func (e *RdwEncoder) writeBlock() error {
+	if len(e.block) == 0 {
+		return nil
+	}
+	var bdw [4]byte
+	n := len(e.block) + 4
+	if n > 0x7fff {
+		binary.BigEndian.PutUint32(bdw[:], uint32(n)|1<<31)
+	} else {
+		binary.BigEndian.PutUint16(bdw[:2], uint16(n))
+	}
+	if _, err := e.w.Write(bdw[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(e.block)
+	e.block = e.block[:0]
+	return err
+}
@@ -0,0 +1,109 @@
+package gofixedlength
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type rdwTest struct {
+	Code  string `fixed:"0-1" validate:"oneof=A B"`
+	Index int    `fixed:"1-4"`
+	Note  string `fixed:"4-7"`
+}
+
+func TestRdwRoundTrip(t *testing.T) {
+	for _, blockSize := range []int{0, 60, 27998} {
+		var b bytes.Buffer
+		e := NewRdwEncoder(&b, blockSize)
+		for i := 0; i < 10; i++ {
+			if err := e.WriteRecord([]byte(fmt.Sprintf("A%03d%s", i, strings.Repeat("x", i)))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := e.Encode(rdwTest{Code: "X"}); err == nil {
+			t.Errorf("Expected a validation error")
+		}
+		if err := e.Flush(); err != nil {
+			t.Fatal(err)
+		}
+
+		d := NewRdwDecoder(&b, blockSize > 0)
+		for i := 0; i < 10; i++ {
+			var v rdwTest
+			// Notes cut short are left out, as by Unmarshal
+			note := ""
+			if i >= 3 {
+				note = "xxx"
+			}
+			if err := d.Decode(&v); err != nil || v.Index != i || v.Note != note {
+				t.Errorf("Block size %d: expected record %d, got %+v (%v)", blockSize, i, v, err)
+			}
+		}
+		if _, err := d.ReadRecord(); err != io.EOF || d.Records() != 10 {
+			t.Errorf("Block size %d: expected io.EOF after 10 records, got %v after %d", blockSize, err, d.Records())
+		}
+	}
+
+	if err := NewRdwEncoder(&bytes.Buffer{}, 60).WriteRecord(make([]byte, 53)); err != ErrRecordTooLong {
+		t.Errorf("Expected ErrRecordTooLong, got %v", err)
+	}
+}
+
+func TestRdwDecoder(t *testing.T) {
+	for _, test := range []struct {
+		data    string
+		blocked bool
+		records []string
+		err     error
+	}{
+		// Spanned record, in a block with an extended BDW
+		{"\x80\x00\x00\x1c\x00\x07\x01\x00A00\x00\x06\x03\x001x\x00\x06\x02\x00yz\x00\x05\x00\x00B", true, []string{"A001xyz", "B"}, io.EOF},
+		// Empty block
+		{"\x00\x04\x00\x00\x00\x09\x00\x00\x00\x05\x00\x00A", true, []string{"A"}, io.EOF},
+		{"\x00\x09\x00\x00\x00\x05\x00\x00A", true, []string{"A"}, io.EOF},
+		{"\x00\x09\x00\x01", true, nil, ErrInvalidBDW},
+		{"\x00\x09\x00\x00\x00\x06\x00\x00A", true, nil, ErrInvalidRDW},
+		{"\x00\x0a\x00\x00\x00\x06\x00\x00A", true, nil, io.ErrUnexpectedEOF},
+		{"\x00\x05\x00\x00A\x00\x06\x02\x00BC", false, []string{"A"}, ErrInvalidRDW},
+		{"\x00\x05\x00\x00A\x00\x06\x01\x00BC", false, []string{"A"}, io.ErrUnexpectedEOF},
+		{"\x00\x03\x00\x00", false, nil, ErrInvalidRDW},
+	} {
+		d := NewRdwDecoder(strings.NewReader(test.data), test.blocked)
+		var records []string
+		var err error
+		for {
+			var record []byte
+			if record, err = d.ReadRecord(); err != nil {
+				break
+			}
+			records = append(records, string(record))
+		}
+		if strings.Join(records, "|") != strings.Join(test.records, "|") || !errors.Is(err, test.err) {
+			t.Errorf("%q: got %q (%v), expected %q (%v)", test.data, records, err, test.records, test.err)
+		}
+	}
+}
+
+func TestRdwDecoderContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := NewRdwDecoder(strings.NewReader("\x80\x00\x00\x1c\x00\x07\x01\x00A00\x00\x06\x03\x001x\x00\x06\x02\x00yz\x00\x05\x00\x00B"), true)
+	var v rdwTest
+	if err := d.DecodeContext(ctx, &v); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	err := d.DecodeContext(ctx, &v)
+	var progressErr *ProgressError
+	if !errors.As(err, &progressErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a ProgressError, got %v", err)
+	}
+	if progressErr.Progress != (Progress{Records: 1, Lines: 1, Offset: 23}) || v.Note != "xyz" {
+		t.Errorf("Expected 1 record up to offset 23, got %+v", progressErr.Progress)
+	}
+}
@@ -0,0 +1,204 @@
+package gofixedlength
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	ErrLayoutRange   = errors.New("Invalid layout field range")
+	ErrDuplicateName = errors.New("Duplicate name in schema")
+)
+
+// Schema describes the record types of a file at runtime. It can be loaded
+// from a JSON document with LoadSchema, like:
+//
+//	{
+//		"name": "payments",
+//		"records": [
+//			{"name": "header", "begin": 0, "end": 1, "code": "1", "fields": [
+//				{"name": "Date", "begin": 1, "end": 9, "type": "time", "format": "20060102"}
+//			]},
+//			{"name": "detail", "begin": 0, "end": 1, "code": "6", "fields": [
+//				{"name": "Account", "begin": 1, "end": 11},
+//				{"name": "Amount", "begin": 11, "end": 21, "type": "float", "format": "2"}
+//			]}
+//		]
+//	}
+//
+// The struct carries `yaml` tags as well, so YAML documents can be decoded
+// into it with any YAML package and checked with Validate.
+type Schema struct {
+	Name    string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Records []RecordLayout `json:"records" yaml:"records"`
+}
+
+// RecordLayout describes a record type, identified by the code found in
+// its range as with the `record:"0-1,6"` tag. A record type without a code
+// matches any record.
+type RecordLayout struct {
+	Name   string `json:"name" yaml:"name"`
+	Begin  int    `json:"begin,omitempty" yaml:"begin,omitempty"`
+	End    int    `json:"end,omitempty" yaml:"end,omitempty"`
+	Code   string `json:"code,omitempty" yaml:"code,omitempty"`
+	Fields Layout `json:"fields" yaml:"fields"`
+}
+
+// Record holds the values of a record decoded with a Schema.
+type Record struct {
+	Type   string // Name of the record type
+	Values map[string]interface{}
+}
+
+// SchemaError reports an invalid record type or field of a schema.
+type SchemaError struct {
+	Record string
+	Field  string // Empty for errors about the record type itself
+	Err    error
+}
+
+func (e *SchemaError) Error() string {
+	if e.Field != "" {
+		return e.Err.Error() + ": " + e.Record + "." + e.Field
+	}
+	return e.Err.Error() + ": " + e.Record
+}
+
+// Unwrap returns the underlying error.
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// LoadSchema reads a JSON schema and validates it.
+func LoadSchema(r io.Reader) (*Schema, error) {
+	var s Schema
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LoadSchemaFile reads a JSON schema from a file and validates it.
+func LoadSchemaFile(filename string) (*Schema, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadSchema(f)
+}
+
+// Validate checks that names are unique, and that fields have known types,
+// locations and valid ranges. Errors are returned as *SchemaError.
+func (s *Schema) Validate() error {
+	records := make(map[string]bool)
+	for _, r := range s.Records {
+		if records[r.Name] {
+			return &SchemaError{Record: r.Name, Err: ErrDuplicateName}
+		}
+		records[r.Name] = true
+		if r.Code != "" && (r.Begin < 0 || r.End-r.Begin != len(r.Code)) {
+			return &SchemaError{Record: r.Name, Err: ErrLayoutRange}
+		}
+
+		fields := make(map[string]bool)
+		for _, f := range r.Fields {
+			if fields[f.Name] {
+				return &SchemaError{Record: r.Name, Field: f.Name, Err: ErrDuplicateName}
+			}
+			fields[f.Name] = true
+			if f.Begin < 0 || f.End <= f.Begin {
+				return &SchemaError{Record: r.Name, Field: f.Name, Err: ErrLayoutRange}
+			}
+			if _, err := f.goType(); err != nil {
+				return &SchemaError{Record: r.Name, Field: f.Name, Err: err}
+			}
+			if _, err := f.options(); err != nil {
+				return &SchemaError{Record: r.Name, Field: f.Name, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the first record type matching the record. Codes are
+// located by byte offsets, as fields are by Unmarshal.
+func (s *Schema) Lookup(data string) (*RecordLayout, bool) {
+	for i := range s.Records {
+		r := &s.Records[i]
+		if r.Code == "" || r.Begin >= 0 && r.Begin <= r.End && r.End <= len(data) && data[r.Begin:r.End] == r.Code {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// Unmarshal decodes a record with UnmarshalToMap, using the layout of its
+// record type. Records matching no type return ErrUnexpectedRecord.
+func (s *Schema) Unmarshal(data string) (Record, error) {
+	r, ok := s.Lookup(data)
+	if !ok {
+		return Record{}, ErrUnexpectedRecord
+	}
+	values, err := UnmarshalToMap(data, r.Fields)
+	return Record{Type: r.Name, Values: values}, err
+}
+
+// Marshal encodes a record with MarshalFromMap, using the layout of its
+// record type. The code of the record type is written in its range, at
+// the byte offsets Lookup reads it from.
+func (s *Schema) Marshal(rec Record) (string, error) {
+	for _, r := range s.Records {
+		if r.Name != rec.Type {
+			continue
+		}
+		line, err := MarshalFromMap(rec.Values, r.Fields)
+		if err != nil || r.Code == "" {
+			return line, err
+		}
+		if r.Begin < 0 || r.Begin > r.End || r.End-r.Begin != len(r.Code) {
+			return line, ErrLayoutRange
+		}
+		if len(line) < r.End {
+			line += strings.Repeat(" ", r.End-len(line))
+		}
+		return line[:r.Begin] + r.Code + line[r.End:], nil
+	}
+	return "", ErrUnexpectedRecord
+}
+
+// UnmarshalRecords decodes a sequence of records (as returned by
+// RecordsFromFile), skipping empty ones. Errors are returned as *LineError.
+func (s *Schema) UnmarshalRecords(records []string) ([]Record, error) {
+	var out []Record
+	for i, data := range records {
+		if strings.TrimSpace(data) == "" {
+			continue
+		}
+		rec, err := s.Unmarshal(data)
+		if err != nil {
+			return out, &LineError{Line: i + 1, Err: err}
+		}
+		out = append(out, rec)
+	}
+	return out, nil
+}
+
+// MarshalRecords encodes a sequence of records with Marshal.
+func (s *Schema) MarshalRecords(records []Record) ([]string, error) {
+	out := make([]string, 0, len(records))
+	for _, rec := range records {
+		line, err := s.Marshal(rec)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, line)
+	}
+	return out, nil
+}
@@ -0,0 +1,85 @@
+package gofixedlength
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const schemaTest = `{
+	"name": "payments",
+	"records": [
+		{"name": "header", "begin": 0, "end": 1, "code": "1", "fields": [
+			{"name": "Date", "begin": 1, "end": 9, "type": "time", "format": "20060102"}
+		]},
+		{"name": "detail", "begin": 0, "end": 1, "code": "6", "fields": [
+			{"name": "Account", "begin": 1, "end": 11},
+			{"name": "Amount", "begin": 11, "end": 21, "type": "float", "format": "2"}
+		]}
+	]
+}`
+
+func TestSchema(t *testing.T) {
+	s, err := LoadSchema(strings.NewReader(schemaTest))
+	if err != nil {
+		t.Fatalf("LoadSchema failed: %v", err)
+	}
+
+	records := []string{"120150114", "6ACC1      0000012.50", "", "6ACC2      0000100.00"}
+	out, err := s.UnmarshalRecords(records)
+	if err != nil || len(out) != 3 {
+		t.Fatalf("Decoded %v (%v)", out, err)
+	}
+	if date, ok := out[0].Values["Date"].(time.Time); out[0].Type != "header" || !ok || date.Day() != 14 {
+		t.Errorf("Header decoded as %v", out[0])
+	}
+	if out[2].Type != "detail" || out[2].Values["Account"] != "ACC2" || out[2].Values["Amount"] != 100.0 {
+		t.Errorf("Detail decoded as %v", out[2])
+	}
+
+	lines, err := s.MarshalRecords(out)
+	if err != nil || len(lines) != 3 || lines[0] != records[0] || lines[1] != records[1] {
+		t.Errorf("Encoded as %q (%v)", lines, err)
+	}
+
+	_, err = s.UnmarshalRecords([]string{"120150114", "9"})
+	if lineErr, ok := err.(*LineError); !ok || lineErr.Line != 2 || lineErr.Err != ErrUnexpectedRecord {
+		t.Errorf("Expected unexpected record on line 2, got %v", err)
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		schema string
+		err    error
+	}{
+		{`{"records": [{"name": "a"}, {"name": "a"}]}`, ErrDuplicateName},
+		{`{"records": [{"name": "a", "fields": [{"name": "x", "begin": 2, "end": 1}]}]}`, ErrLayoutRange},
+		{`{"records": [{"name": "a", "fields": [{"name": "x", "begin": 0, "end": 1, "type": "money"}]}]}`, ErrLayoutType},
+		{`{"records": [{"name": "a", "begin": 0, "end": 2, "code": "1"}]}`, ErrLayoutRange},
+	}
+	for _, test := range tests {
+		_, err := LoadSchema(strings.NewReader(test.schema))
+		if schemaErr, ok := err.(*SchemaError); !ok || schemaErr.Err != test.err {
+			t.Errorf("%s: expected %v, got %v", test.schema, test.err, err)
+		}
+	}
+}
+
+func TestSchemaCodeOffsets(t *testing.T) {
+	s := &Schema{Records: []RecordLayout{
+		{Name: "broken", Code: "AB", Begin: 3, End: 1},
+		{Name: "detail", Code: "AB", Begin: 4, End: 6, Fields: Layout{{Name: "name", Begin: 0, End: 4}}},
+	}}
+	line, err := s.Marshal(Record{Type: "detail", Values: map[string]interface{}{"name": "é"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, ok := s.Lookup(line)
+	if !ok || r.Name != "detail" {
+		t.Errorf("%q: expected a detail record, got %v", line, r)
+	}
+	if _, err := s.Marshal(Record{Type: "broken"}); err != ErrLayoutRange {
+		t.Errorf("Expected ErrLayoutRange, got %v", err)
+	}
+}
@@ -0,0 +1,412 @@
+package gofixedlength
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidTarget    = errors.New("Target must be a non-nil pointer to a struct")
+	ErrMissingRecord    = errors.New("A record required by the file structure is missing")
+	ErrUnexpectedRecord = errors.New("Record does not fit the file structure")
+)
+
+// UnmarshalRecords decodes a sequence of records (as returned by
+// RecordsFromFile) into a nested structure describing the grammar of the
+// whole file. This should resemble:
+//
+// 	type File struct {
+// 		Header  FileHeader  `record:"0-1,1"` // Record type "1" in column 0
+// 		Batches []Batch
+// 		Trailer FileTrailer `record:"0-1,9"`
+// 	}
+//	type Batch struct {
+//		Header  BatchHeader  `record:"0-1,5"`
+//		Details []Detail     `record:"0-1,6"`
+//		Trailer BatchTrailer `record:"0-1,8"`
+//	}
+//
+//	var out File
+//	err := UnmarshalRecords(records, &out)
+//
+// Fields having a `record` tag are single records, identified by the text
+// found in the given range, and are decoded with Unmarshal. Struct fields
+// without a `record` tag are groups of records, which start with the first
+// record they declare; other fields, like times or structs declaring no
+// records, are left alone. A plain field is required, a pointer is optional and
+// a slice may be repeated any number of times. Empty records are skipped.
+func UnmarshalRecords(records []string, v interface{}) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	p := &recordParser{records: records}
+	if err := p.parseGroup(val.Elem(), ""); err != nil {
+		return err
+	}
+	if _, ok := p.peek(); ok {
+		return &LineError{Line: p.pos + 1, Err: ErrUnexpectedRecord}
+	}
+	return nil
+}
+
+// MarshalRecords is the inverse of UnmarshalRecords: it walks the nested
+// structure and marshals every record, in order, with Marshal. Control
+// fields (see the `control` tag) are computed before marshalling, inner
+// groups first, on a copy of the structure.
+func MarshalRecords(v interface{}) ([]string, error) {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return nil, ErrInvalidTarget
+	}
+	val = copyGroup(val)
+	if err := controlGroup(val); err != nil {
+		return nil, err
+	}
+	var out []string
+	err := marshalGroup(val, &out)
+	return out, err
+}
+
+// copyGroup returns a copy of a group which shares no records or groups
+// with it, so that its control fields can be set.
+func copyGroup(val reflect.Value) reflect.Value {
+	out := reflect.New(val.Type()).Elem()
+	out.Set(val)
+	for _, f := range structureFields(val.Type()) {
+		field := out.FieldByName(f.name)
+		copyElem := func(elem reflect.Value) reflect.Value {
+			if f.isRecord {
+				return elem
+			}
+			return copyGroup(elem)
+		}
+		switch {
+		case f.repeated:
+			if field.IsNil() {
+				continue
+			}
+			elems := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			for i := 0; i < field.Len(); i++ {
+				elem := field.Index(i)
+				if !f.elemPtr {
+					elems.Index(i).Set(copyElem(elem))
+				} else if !elem.IsNil() {
+					elems.Index(i).Set(reflect.New(f.elemType))
+					elems.Index(i).Elem().Set(copyElem(elem.Elem()))
+				}
+			}
+			field.Set(elems)
+		case f.optional:
+			if !field.IsNil() {
+				elem := reflect.New(f.elemType)
+				elem.Elem().Set(copyElem(field.Elem()))
+				field.Set(elem)
+			}
+		case !f.isRecord:
+			field.Set(copyGroup(field))
+		}
+	}
+	return out
+}
+
+// controlGroup sets the control fields of the records of a group, once
+// those of its inner groups are set.
+func controlGroup(val reflect.Value) error {
+	fields := structureFields(val.Type())
+	for _, f := range fields {
+		if f.isRecord {
+			continue
+		}
+		for _, elem := range f.elements(val) {
+			if err := controlGroup(elem); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range fields {
+		if !f.isRecord {
+			continue
+		}
+		for _, elem := range f.elements(val) {
+			if err := applyControls(val, f.name, elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func marshalGroup(val reflect.Value, out *[]string) error {
+	for _, f := range structureFields(val.Type()) {
+		for _, elem := range f.elements(val) {
+			if !f.isRecord {
+				if err := marshalGroup(elem, out); err != nil {
+					return err
+				}
+				continue
+			}
+			line, err := Marshal(elem.Interface())
+			if err != nil {
+				return err
+			}
+			*out = append(*out, line)
+		}
+	}
+	return nil
+}
+
+// structureField describes a field taking part in a file structure.
+type structureField struct {
+	name     string
+	isRecord bool         // A single record, otherwise a group of records
+	elemType reflect.Type // Struct type of the record or of the group
+	optional bool         // Pointer field
+	repeated bool         // Slice field
+	elemPtr  bool         // Slice of pointers
+	begin    int
+	end      int
+	code     string
+}
+
+// structureFields returns the fields of a group taking part in the file
+// structure, in declaration order.
+func structureFields(t reflect.Type) []structureField {
+	var fields []structureField
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+		tag := typeField.Tag.Get("record")
+		if tag == "-" || typeField.PkgPath != "" {
+			continue
+		}
+
+		f := structureField{name: typeField.Name, elemType: typeField.Type}
+		switch f.elemType.Kind() {
+		case reflect.Ptr:
+			f.optional = true
+			f.elemType = f.elemType.Elem()
+		case reflect.Slice:
+			f.repeated = true
+			f.elemType = f.elemType.Elem()
+			if f.elemType.Kind() == reflect.Ptr {
+				f.elemPtr = true
+				f.elemType = f.elemType.Elem()
+			}
+		}
+		if f.elemType.Kind() != reflect.Struct {
+			continue
+		}
+		if tag == "" && !isGroupType(f.elemType, make(map[reflect.Type]bool)) {
+			continue
+		}
+
+		if tag != "" {
+			cArguments := strings.SplitN(tag, ",", 2)
+			cBookend := strings.Split(cArguments[0], "-")
+			if len(cArguments) != 2 || len(cBookend) != 2 {
+				continue
+			}
+			f.isRecord = true
+			f.begin, _ = strconv.Atoi(cBookend[0])
+			f.end, _ = strconv.Atoi(cBookend[1])
+			f.code = cArguments[1]
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// isGroupType reports whether an untagged struct type is a group of
+// records: it has to declare records, directly or through its own groups,
+// and not be a value like time.Time or a type decoding itself.
+func isGroupType(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] || isTimeType(t) ||
+		reflect.PtrTo(t).Implements(reflect.TypeOf((*FixedUnmarshaler)(nil)).Elem()) ||
+		t.Implements(reflect.TypeOf((*FixedMarshaler)(nil)).Elem()) {
+		return false
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
+		tag := typeField.Tag.Get("record")
+		if tag == "-" || typeField.PkgPath != "" {
+			continue
+		}
+		sub := typeField.Type
+		for sub.Kind() == reflect.Ptr || sub.Kind() == reflect.Slice {
+			sub = sub.Elem()
+		}
+		if sub.Kind() != reflect.Struct {
+			continue
+		}
+		if tag != "" || isGroupType(sub, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// starts reports whether the given record can be the first one of the
+// field.
+func (f structureField) starts(record string) bool {
+	if f.isRecord {
+		return f.begin >= 0 && f.end <= len(record) && f.begin <= f.end && record[f.begin:f.end] == f.code
+	}
+	return groupStarts(f.elemType, record)
+}
+
+// groupStarts reports whether the given record can be the first one of a
+// group: it has to match one of the leading fields, up to the first
+// required one.
+func groupStarts(t reflect.Type, record string) bool {
+	for _, f := range structureFields(t) {
+		if f.starts(record) {
+			return true
+		}
+		if !f.optional && !f.repeated {
+			return false
+		}
+	}
+	return false
+}
+
+type recordParser struct {
+	records []string
+	pos     int
+}
+
+// peek returns the next non-empty record without consuming it.
+func (p *recordParser) peek() (string, bool) {
+	for p.pos < len(p.records) && p.records[p.pos] == "" {
+		p.pos++
+	}
+	if p.pos >= len(p.records) {
+		return "", false
+	}
+	return p.records[p.pos], true
+}
+
+func (p *recordParser) parseGroup(val reflect.Value, path string) error {
+	lines := make(map[string]int) // Line of the last record of every field
+	for _, f := range structureFields(val.Type()) {
+		field := val.FieldByName(f.name)
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+
+		switch {
+		case f.repeated:
+			field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+			for {
+				record, ok := p.peek()
+				if !ok || !f.starts(record) {
+					break
+				}
+				elem := reflect.New(f.elemType)
+				lines[f.name] = p.pos + 1
+				if err := p.parseElement(f, elem.Elem(), fieldPath+"["+strconv.Itoa(field.Len())+"]"); err != nil {
+					return err
+				}
+				if f.elemPtr {
+					field.Set(reflect.Append(field, elem))
+				} else {
+					field.Set(reflect.Append(field, elem.Elem()))
+				}
+			}
+		case f.optional:
+			if record, ok := p.peek(); ok && f.starts(record) {
+				elem := reflect.New(f.elemType)
+				lines[f.name] = p.pos + 1
+				if err := p.parseElement(f, elem.Elem(), fieldPath); err != nil {
+					return err
+				}
+				field.Set(elem)
+			}
+		default:
+			record, ok := p.peek()
+			if !ok {
+				return &LineError{Line: p.pos + 1, Field: fieldPath, Err: ErrMissingRecord}
+			}
+			if !f.starts(record) {
+				return &LineError{Line: p.pos + 1, Field: fieldPath, Err: ErrUnexpectedRecord}
+			}
+			lines[f.name] = p.pos + 1
+			if err := p.parseElement(f, field, fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range structureFields(val.Type()) {
+		if !f.isRecord {
+			continue
+		}
+		for _, elem := range f.elements(val) {
+			if err := checkControls(val, f.name, elem); err != nil {
+				fieldPath := f.name
+				if path != "" {
+					fieldPath = path + "." + f.name
+				}
+				return &LineError{Line: lines[f.name], Field: fieldPath, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *recordParser) parseElement(f structureField, val reflect.Value, path string) error {
+	if !f.isRecord {
+		return p.parseGroup(val, path)
+	}
+	if err := Unmarshal(p.records[p.pos], val.Addr().Interface()); err != nil {
+		return &LineError{Line: p.pos + 1, Field: path, Err: err}
+	}
+	p.pos++
+	return nil
+}
+
+// elements returns the values held by a structure field of a group.
+func (f structureField) elements(group reflect.Value) []reflect.Value {
+	field := group.FieldByName(f.name)
+	var elems []reflect.Value
+	switch {
+	case f.repeated:
+		for i := 0; i < field.Len(); i++ {
+			if elem := reflect.Indirect(field.Index(i)); elem.IsValid() {
+				elems = append(elems, elem)
+			}
+		}
+	case f.optional:
+		if !field.IsNil() {
+			elems = append(elems, field.Elem())
+		}
+	default:
+		elems = append(elems, field)
+	}
+	return elems
+}
+
+// walkElements calls fn for every record and group of a file structure,
+// in order, except for the direct child field named skip. Groups are
+// visited before their records.
+func walkElements(val reflect.Value, skip string, fn func(elem reflect.Value, isRecord bool) error) error {
+	for _, f := range structureFields(val.Type()) {
+		if f.name == skip {
+			continue
+		}
+		for _, elem := range f.elements(val) {
+			if err := fn(elem, f.isRecord); err != nil {
+				return err
+			}
+			if !f.isRecord {
+				if err := walkElements(elem, "", fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
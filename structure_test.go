@@ -0,0 +1,148 @@
+package gofixedlength
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type structureFile struct {
+	Header  structureFileHeader `record:"0-1,1"`
+	Batches []structureBatch
+	Trailer structureTrailer `record:"0-1,9"`
+}
+
+type structureBatch struct {
+	Header  structureBatchHeader `record:"0-1,5"`
+	Details []structureDetail    `record:"0-1,6"`
+	Trailer structureTrailer     `record:"0-1,8"`
+}
+
+type structureFileHeader struct {
+	Type string `fixed:"0-1"`
+	Name string `fixed:"1-6"`
+}
+
+type structureBatchHeader struct {
+	Type  string `fixed:"0-1"`
+	Batch int    `fixed:"1-3"`
+}
+
+type structureDetail struct {
+	Type   string `fixed:"0-1"`
+	Amount int    `fixed:"1-6"`
+}
+
+type structureTrailer struct {
+	Type  string `fixed:"0-1"`
+	Count int    `fixed:"1-4"`
+}
+
+var structureTestRecords = []string{
+	"1ACME ",
+	"501",
+	"600100",
+	"600200",
+	"8002",
+	"502",
+	"8000",
+	"9002",
+	"",
+}
+
+func TestUnmarshalRecords(t *testing.T) {
+	var out structureFile
+	if err := UnmarshalRecords(structureTestRecords, &out); err != nil {
+		t.Fatalf("UnmarshalRecords failed: %v", err)
+	}
+	if out.Header.Name != "ACME" {
+		t.Errorf("Header.Name parsed as '%s'", out.Header.Name)
+	}
+	if len(out.Batches) != 2 {
+		t.Fatalf("Parsed %d batches", len(out.Batches))
+	}
+	if out.Batches[0].Header.Batch != 1 || len(out.Batches[0].Details) != 2 {
+		t.Errorf("First batch parsed as %+v", out.Batches[0])
+	}
+	if out.Batches[0].Details[1].Amount != 200 {
+		t.Errorf("Second detail amount parsed as %d", out.Batches[0].Details[1].Amount)
+	}
+	if out.Batches[1].Header.Batch != 2 || len(out.Batches[1].Details) != 0 {
+		t.Errorf("Second batch parsed as %+v", out.Batches[1])
+	}
+	if out.Trailer.Count != 2 {
+		t.Errorf("Trailer.Count parsed as %d", out.Trailer.Count)
+	}
+}
+
+func TestUnmarshalRecordsOrdering(t *testing.T) {
+	tests := []struct {
+		records []string
+		line    int
+		err     error
+	}{
+		{[]string{"1ACME ", "600100", "9000"}, 2, ErrUnexpectedRecord},
+		{[]string{"1ACME ", "501", "600100", "9000"}, 4, ErrUnexpectedRecord},
+		{[]string{"1ACME ", "501", "8001"}, 4, ErrMissingRecord},
+		{[]string{"1ACME ", "9000", "600100"}, 3, ErrUnexpectedRecord},
+	}
+	for _, test := range tests {
+		var out structureFile
+		err := UnmarshalRecords(test.records, &out)
+		lineErr, ok := err.(*LineError)
+		if !ok {
+			t.Errorf("%v: expected a *LineError, got %v", test.records, err)
+			continue
+		}
+		if lineErr.Line != test.line || !errors.Is(err, test.err) {
+			t.Errorf("%v: got error %v", test.records, err)
+		}
+	}
+}
+
+func TestMarshalRecords(t *testing.T) {
+	var in structureFile
+	if err := UnmarshalRecords(structureTestRecords, &in); err != nil {
+		t.Fatalf("UnmarshalRecords failed: %v", err)
+	}
+	out, err := MarshalRecords(in)
+	if err != nil {
+		t.Fatalf("MarshalRecords failed: %v", err)
+	}
+	expected := structureTestRecords[:len(structureTestRecords)-1]
+	if len(out) != len(expected) {
+		t.Fatalf("Marshalled %d records", len(out))
+	}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Errorf("Record %d marshalled as '%s'", i, out[i])
+		}
+	}
+}
+
+type structureInfo struct {
+	Source string
+}
+
+type structureMetaFile struct {
+	Imported time.Time
+	Info     structureInfo
+	Header   structureFileHeader `record:"0-1,1"`
+	Batches  []structureBatch
+	Trailer  structureTrailer `record:"0-1,9"`
+	checked  *structureBatch
+}
+
+func TestUnmarshalRecordsPlainFields(t *testing.T) {
+	out := structureMetaFile{Info: structureInfo{"upload"}}
+	if err := UnmarshalRecords(structureTestRecords, &out); err != nil {
+		t.Fatalf("UnmarshalRecords failed: %v", err)
+	}
+	if len(out.Batches) != 2 || out.Trailer.Count != 2 || out.Info.Source != "upload" || !out.Imported.IsZero() || out.checked != nil {
+		t.Errorf("Parsed as %+v", out)
+	}
+	lines, err := MarshalRecords(out)
+	if err != nil || len(lines) != len(structureTestRecords)-1 {
+		t.Errorf("Marshalled as %q (%v)", lines, err)
+	}
+}
@@ -0,0 +1,139 @@
+package gofixedlength
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MarshalCsv marshals struct data into a delimited line, placing every field
+// at the index given in its `csv` tag. This should resemble:
+//
+//	type SomeType struct {
+//		ValA string        `csv:"0"`
+//		ValB int           `csv:"1"`
+//		ValC *EmbeddedType `csv:"2" csvsplit:"~"`
+//	}
+//	type EmbeddedType struct {
+//		ValX string `csv:"0"`
+//		ValY string `csv:"1"`
+//	}
+//
+//	out, err := MarshalCsv(SomeType{"A", 2, &EmbeddedType{"X", "Y"}}, ",")
+//	// out == "A,2,X~Y"
+//
+// Embedded objects are joined with their `csvsplit` separator, or marshalled
+// with Marshal if they have a `csvlayout:"fixed"` tag, and slices are joined
+// with their `csvsplit` separator or padded to their `listwidth`. Missing
+// indexes are left empty and fields containing separators, quotes or line
+// breaks are quoted as in RFC 4180. Floating-point values are printed with
+// the decimals following the column, as in `csv:"2,3"`, or with the shortest
+// representation, honouring DECIMAL_COMMA. Times are printed with the layout
+// following the column, as in `csv:"3,2006-01-02"`.
+// Values are checked against their `validate` tags (see Validate) first.
+func MarshalCsv(v interface{}, sep string) (string, error) {
+	return MarshalCsvOptions(v, CsvOptions{Separator: sep})
+}
+
+// MarshalCsvOptions works like MarshalCsv, with the quoting configured by
+// opts. Fields tagged with a column name are placed according to
+// opts.Header, and skipped if it lacks them; rows have as many columns as
+// the header.
+func MarshalCsvOptions(v interface{}, opts CsvOptions) (string, error) {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return "", ErrInvalidTarget
+	}
+	if err := validateCsvStruct(val, true, opts.Header); err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for i := 0; i < val.NumField(); i++ {
+		typeField := val.Type().Field(i)
+		tag := typeField.Tag
+
+		cField, cFormat := csvTag(tag)
+		f, ok := csvColumn(cField, opts.Header)
+		if !ok || f < 0 {
+			continue
+		}
+
+		field := val.Field(i)
+		fOpts, err := newFieldOptions(typeField, cFormat, opts.Location)
+		if err != nil {
+			return "", err
+		}
+		s, handled := encodeValue(field, fOpts)
+		switch {
+		case handled:
+			if fOpts.check != "" && isIntegerKind(field.Kind()) {
+				s, err = applyCheckDigit(fOpts.check, padCheckDigit(fOpts.check, s, 0))
+			} else if fOpts.check != "" && field.Kind() == reflect.String && strings.TrimSpace(s) != "" {
+				s, err = applyCheckDigit(fOpts.check, strings.TrimSpace(s))
+			}
+		case typeField.Type.Kind() == reflect.Slice:
+			lOpts, ok := newListOptions(tag, "csvsplit")
+			if !ok {
+				continue
+			}
+			s, err = encodeList(field, lOpts, fOpts)
+		case typeField.Type.Kind() == reflect.Ptr || typeField.Type.Kind() == reflect.Struct:
+			cSep := tag.Get("csvsplit")
+			fixedLayout := tag.Get("csvlayout") == "fixed"
+			if cSep == "" && !fixedLayout || typeField.Type.Kind() == reflect.Ptr && field.IsNil() {
+				continue
+			}
+			if fixedLayout {
+				s, err = Marshal(field.Interface())
+			} else {
+				subOpts := opts
+				subOpts.Separator = cSep
+				subOpts.Header = nil
+				s, err = MarshalCsvOptions(field.Interface(), subOpts)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		for len(parts) <= f {
+			parts = append(parts, "")
+		}
+		parts[f] = s
+	}
+	// Rows span the whole header
+	for len(parts) < len(opts.Header) {
+		parts = append(parts, "")
+	}
+	return joinCsv(parts, opts), nil
+}
+
+// joinCsv joins fields into a delimited record, quoting them when needed.
+func joinCsv(parts []string, opts CsvOptions) string {
+	sep := opts.Separator
+	if sep == "" {
+		sep = ","
+	}
+	quote := string(opts.Quote)
+	if opts.Quote == 0 {
+		quote = `"`
+	}
+
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		if strings.Contains(part, sep) || strings.Contains(part, quote) || strings.ContainsAny(part, "\r\n") ||
+			opts.TrimLeadingSpace && strings.IndexAny(part, " \t") == 0 {
+			b.WriteString(quote)
+			b.WriteString(strings.Replace(part, quote, quote+quote, -1))
+			b.WriteString(quote)
+		} else {
+			b.WriteString(part)
+		}
+	}
+	return b.String()
+}
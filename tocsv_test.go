@@ -0,0 +1,59 @@
+package gofixedlength
+
+import "testing"
+
+type csvMarshalTest struct {
+	StringA string         `csv:"0"`
+	NumberB int            `csv:"1"`
+	FloatC  float64        `csv:"2"`
+	Date    *csvDateStruct `csv:"3" csvsplit:"-"`
+	BoolE   bool           `csv:"5"`
+	Count   uint           `csv:"6"`
+	RawLine string         `csv:"raw"`
+}
+
+func TestMarshalCsv(t *testing.T) {
+	in := csvMarshalTest{
+		StringA: "Say \"hi\", then\nleave",
+		NumberB: -12,
+		FloatC:  3.25,
+		Date:    &csvDateStruct{2009, 10, 10},
+		BoolE:   true,
+		Count:   7,
+		RawLine: "ignored",
+	}
+	out, err := MarshalCsv(in, ",")
+	if err != nil {
+		t.Fatalf("MarshalCsv failed: %v", err)
+	}
+	expected := "\"Say \"\"hi\"\", then\nleave\",-12,3.25,2009-10-10,,true,7"
+	if out != expected {
+		t.Errorf("Marshalled as '%s'", out)
+	}
+
+	var back csvMarshalTest
+	if err := UnmarshalCsv(out, ",", &back); err != nil {
+		t.Fatalf("UnmarshalCsv failed: %v", err)
+	}
+	if back.StringA != in.StringA || back.NumberB != in.NumberB || back.FloatC != in.FloatC ||
+		*back.Date != *in.Date || back.BoolE != in.BoolE || back.Count != in.Count {
+		t.Errorf("Round trip gave %+v", back)
+	}
+}
+
+func TestMarshalCsvWithComma(t *testing.T) {
+	previousValue := DECIMAL_COMMA
+	DECIMAL_COMMA = true
+	defer func() { DECIMAL_COMMA = previousValue }()
+	out, err := MarshalCsv(csvMarshalTest{FloatC: 1.5}, ",")
+	if err != nil || out != ",0,\"1,5\",,,false,0" {
+		t.Errorf("Marshalled as '%s' (%v)", out, err)
+	}
+}
+
+func TestMarshalCsvOptions(t *testing.T) {
+	out, err := MarshalCsvOptions(&csvMarshalTest{StringA: " A;B"}, CsvOptions{Separator: ";", Quote: '\'', TrimLeadingSpace: true})
+	if err != nil || out != "' A;B';0;0;;;false;0" {
+		t.Errorf("Marshalled as '%s' (%v)", out, err)
+	}
+}
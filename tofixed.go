@@ -2,10 +2,7 @@ package gofixedlength
 
 import (
 	"errors"
-	"fmt"
-	"log"
 	"reflect"
-	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -20,6 +17,12 @@ var (
 
 type Line []rune
 
+// FixedMarshaler is implemented by types encoding fixed-length records
+// without reflection, like the methods written by gofixedgen.
+type FixedMarshaler interface {
+	MarshalFixed() (string, error)
+}
+
 // Marshal marshals struct data into a fixed-lenght formatted string.
 //
 // 	type SomeType struct {
@@ -40,130 +43,81 @@ type Line []rune
 //	// out == "this      00000123452015-01-14000123.123"
 //
 // Offsets are zero based.
+// Embedded structs with a range are marshalled into that range, with offsets
+// relative to it, as Unmarshal parses them. Untagged fields are left out.
+// With a `fixedsplit:","` tag they are marshalled with MarshalCsv instead.
+// Slices are joined with their `fixedsplit` separator, or with every item
+// padded to their `listwidth`.
 // Field filling is based on data type: for text types it will be spaces,
 // while numbers will be right-aligned and filled with zeroes.
 // Floating point-values are printed with the specified number of decimals (two by default).
 // time.Time fields are printed in the specified layout.
+// Booleans are left blank.
+// Values are checked against their `validate` tags (see Validate) first.
+// Types implementing FixedMarshaler encode themselves instead.
 func Marshal(v interface{}) (string, error) {
+	if m, ok := v.(FixedMarshaler); ok {
+		return m.MarshalFixed()
+	}
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if err := validateStruct(val, true); err != nil {
+		return "", err
+	}
 	var line Line // Build a rune array the length the output line is supposed to be
-	line = make([]rune, LineLength(v))
+	line = make([]rune, lineLength(val.Type()))
 	//debugStruct(v)
-	var val reflect.Value
-	val = reflect.ValueOf(v)
-	/*
-		var val reflect.Value
-		if reflect.TypeOf(v).Name() != "" {
-			val = reflect.ValueOf(v)
-		} else {
-			val = reflect.ValueOf(v).Elem()
-		}
-	*/
 	for i := 0; i < val.NumField(); i++ {
 		typeField := val.Type().Field(i)
-		tag := typeField.Tag
+		field := val.Field(i)
 
-		cArguments := strings.SplitN(tag.Get("fixed"), ",", 2)
-		var cFormat string
-		if len(cArguments) > 1 {
-			cFormat = cArguments[1]
-		}
-		cRange := cArguments[0]
-		cBookend := strings.Split(cRange, "-")
-		if len(cBookend) != 2 {
+		b, e, cFormat, ok := fixedTag(typeField.Tag)
+		if !ok {
 			// If we don't have two values, skip
 			continue
 		}
-
-		b, _ := strconv.Atoi(cBookend[0])
-		e, _ := strconv.Atoi(cBookend[1])
-
-		/*
-			// Sanity check range before dying miserably
-			if b < 0 || e > len(v) {
-				continue
-			}
-		*/
 		fieldLength := e - b
 
-		//log.Println("CHE C'E QUA DENTRO?", reflect.ValueOf(v).Field(i))
-
-		switch typeField.Type.Kind() {
-		case reflect.Bool:
-			/*
-				format := fmt.Sprintf("%%%dv", fieldLength, fieldLength)
-					if *val.Field(i) {
-						out.WriteString(fmt.Sprintf(format, '1'))
-					} else {
-						out.WriteString(fmt.Sprintf(format, '0'))
-					}
-			*/
-			break
-		case reflect.Float32, reflect.Float64:
-			// cFormat is the number of decimals
-			decimals, err := strconv.Atoi(cFormat)
-			if err != nil {
-				log.Println("Found non-valid format for float:", cFormat)
-			}
-			integerPartLength := fieldLength - 1 - decimals
-			integerPart := int(reflect.ValueOf(v).Field(i).Float())
-			if integerPart >= pow(integerPartLength, 10) {
-				log.Printf("This float number (%v) seems to be too big for output length (%v).\n", integerPart, integerPartLength)
-			}
-			format := fmt.Sprintf("%%0%d.%df", fieldLength, decimals)
-			outstring := fmt.Sprintf(format, reflect.ValueOf(v).Field(i).Float()) // Doesn't check if the source float is too long
-			if DECIMAL_COMMA {
-				outstring = strings.Replace(outstring, ".", ",", 1)
+		opts, err := newFieldOptions(typeField, cFormat, nil)
+		if err != nil {
+			return line.String(), err
+		}
+		handled, err := writeFixed(line, field, opts, b, e)
+		if err != nil {
+			return line.String(), err
+		}
+		if handled {
+			continue
+		}
+		var outstring string
+		if field.Kind() == reflect.Slice {
+			lOpts, ok := newListOptions(typeField.Tag, "fixedsplit")
+			if !ok {
+				continue
 			}
-			err = line.WriteString(outstring, b, e)
+			list, err := encodeList(field, lOpts, opts)
 			if err != nil {
 				return line.String(), err
 			}
-			break
-		case reflect.String:
-			format := fmt.Sprintf("%%-%ds", fieldLength)
-			outstring := fmt.Sprintf(format, reflect.ValueOf(v).Field(i).String())
-			outstring = outstring[0:fieldLength]
-			err := line.WriteString(outstring, b, e)
-			if err != nil {
-				return line.String(), err
+			outstring = padRight(list, fieldLength)
+		} else {
+			// Handle embedded objects by marshalling them into their range
+			sub := reflect.Indirect(field)
+			if sub.Kind() != reflect.Struct {
+				continue
+			}
+			var marshalledStruct string
+			if cSep := typeField.Tag.Get("fixedsplit"); cSep != "" {
+				marshalledStruct, err = MarshalCsv(sub.Interface(), cSep)
+			} else {
+				marshalledStruct, err = Marshal(sub.Interface())
 			}
-			break
-		case reflect.Int8, reflect.Int32, reflect.Int, reflect.Int64, reflect.Uint:
-			format := fmt.Sprintf("%%0%dv", fieldLength)
-			outstring := fmt.Sprintf(format, reflect.ValueOf(v).Field(i).Int())
-			err := line.WriteString(outstring, b, e)
 			if err != nil {
 				return line.String(), err
 			}
-			break
-		case reflect.Ptr, reflect.Struct:
-			if typeField.Type == reflect.TypeOf(time.Time{}) {
-				// cFormat is the time.Format() format
-				if len(cFormat) != fieldLength {
-					log.Println("cFormat for this time.Time object doesn't match the field length") // Maybe this kind of parsing error check should be done elsewhere
-				}
-				outstring := reflect.ValueOf(v).Field(i).Interface().(time.Time).Format(cFormat)
-				line.WriteString(outstring, b, e)
-			} else {
-
-				// Handle embedded objects by recursively parsing
-				// the object with the range we passed.
-				if val.Field(i).IsNil() {
-					// Initialize pointer to avoid panic
-					val.Field(i).Set(reflect.New(val.Field(i).Type().Elem()))
-				}
-				marshalledStruct, err := Marshal(val.Field(i).Interface())
-				if err != nil {
-					return line.String(), err
-				}
-				err = line.WriteString(marshalledStruct, 0, line.Length())
-				if err != nil {
-					return line.String(), err
-				}
-			}
-			break
-		default:
-			break
+			outstring = padRight(marshalledStruct, fieldLength)
+		}
+		if err := line.WriteString(outstring, b, e); err != nil {
+			return line.String(), err
 		}
 	}
 	// Empty runes are changed to space charcter (test no.4)
@@ -175,6 +129,81 @@ func Marshal(v interface{}) (string, error) {
 	return line.String(), nil
 }
 
+// writeFixed encodes a field of a kind encodeFixed handles into its range
+// of line, reporting whether it did. Booleans are left blank.
+func writeFixed(line Line, field reflect.Value, opts fieldOptions, b, e int) (bool, error) {
+	if field.Kind() == reflect.Bool {
+		return true, nil
+	}
+	s, handled, err := encodeFixed(field, opts, e-b)
+	if err != nil || !handled {
+		return handled, err
+	}
+	return true, line.WriteString(s, b, e)
+}
+
+// encodeFixed formats a field for a range of the given width: text is
+// left-aligned and filled with spaces, numbers are right-aligned and filled
+// with zeroes. Floating-point values have two decimals by default.
+func encodeFixed(field reflect.Value, opts fieldOptions, width int) (string, bool, error) {
+	if isTimeType(field.Type()) {
+		if field.Kind() == reflect.Ptr && field.IsNil() {
+			return padRight("", width), true, nil
+		}
+		return FormatTime(reflect.Indirect(field).Interface().(time.Time), opts.format, opts.location, width), true, nil
+	}
+
+	var s string
+	var err error
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, err = applyCheckDigit(opts.check, FormatInt(field.Int(), width))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, err = applyCheckDigit(opts.check, FormatUint(field.Uint(), width))
+	case reflect.Float32, reflect.Float64:
+		s = FormatFloat(field.Float(), field.Type().Bits(), opts.format, width)
+	case reflect.Bool:
+		s = FormatBool(field.Bool(), width)
+	case reflect.String:
+		s = field.String()
+		if opts.check != "" && strings.TrimSpace(s) != "" {
+			s, err = applyCheckDigit(opts.check, strings.TrimSpace(s))
+		}
+		s = FormatString(s, width)
+	default:
+		return "", false, nil
+	}
+	return padRight(s, width), true, err
+}
+
+// padNumber fills a number with zeroes on the left, after its sign.
+func padNumber(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	zeroes := strings.Repeat("0", width-len(s))
+	if strings.HasPrefix(s, "-") {
+		return "-" + zeroes + s[1:]
+	}
+	return zeroes + s
+}
+
+// padRight fills text with spaces on the right.
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// truncate cuts text to the given number of runes.
+func truncate(s string, width int) string {
+	if width < 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	return string([]rune(s)[:width])
+}
+
 func pow(a, b int) int {
 	var c int
 	c = 1
@@ -187,32 +216,22 @@ func pow(a, b int) int {
 // Returns the total length of the line we're going to marshal the data to, iterating
 // all the struct's fields and returning the higher number in the `field` tag.
 func LineLength(v interface{}) int {
-	var higherNumber int
-
-	var val reflect.Value
-	val = reflect.ValueOf(v)
-
-	for i := 0; i < val.NumField(); i++ {
-		typeField := val.Type().Field(i)
-		tag := typeField.Tag
+	return lineLength(reflect.Indirect(reflect.ValueOf(v)).Type())
+}
 
-		cArguments := strings.SplitN(tag.Get("fixed"), ",", 2)
-		cRange := cArguments[0]
-		cBookend := strings.Split(cRange, "-")
+func lineLength(t reflect.Type) int {
+	var higherNumber int
+	for i := 0; i < t.NumField(); i++ {
+		typeField := t.Field(i)
 
-		if len(cBookend) == 2 {
-			if e, _ := strconv.Atoi(cBookend[1]); e > higherNumber {
-				higherNumber = e
-			}
+		if _, e, _, ok := fixedTag(typeField.Tag); ok && e > higherNumber {
+			higherNumber = e
 		}
 
-		// Iterate thgough the embedded struct if it's not a time.Time object
-		if typeField.Type.Kind() == reflect.Struct || typeField.Type.Kind() == reflect.Ptr { // Should I handle the reflect.Ptr case?
-			if typeField.Type != reflect.TypeOf(time.Time{}) {
-				higherSubNumber := LineLength(val.Field(i).Interface())
-				if higherSubNumber > higherNumber {
-					higherNumber = higherSubNumber
-				}
+		// Iterate through the embedded struct if it's not a time.Time object
+		if typeField.Type.Kind() == reflect.Struct && !isTimeType(typeField.Type) {
+			if higherSubNumber := lineLength(typeField.Type); higherSubNumber > higherNumber {
+				higherNumber = higherSubNumber
 			}
 		}
 	}
@@ -1,7 +1,11 @@
 package gofixedlength
 
 import (
+	"bytes"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -269,3 +273,68 @@ func TestMarshalWithComma(t *testing.T) {
 		t.Errorf("Marshalled comma string doesn't match the expected output:\n'%v'\n", out)
 	}
 }
+
+type nestedLengthTest struct {
+	Short embeddedStruct `fixed:"0-10"`
+}
+
+type boolMarshalTest struct {
+	Flag bool   `fixed:"0-1"`
+	Code string `fixed:"0-3"`
+}
+
+func TestMarshalEmbeddedStructs(t *testing.T) {
+	target := testStruct6{
+		Embedded1:      testStruct1{NumberA: 123},
+		AnotherField1:  1,
+		AnotherField2:  2,
+		AnotherField3:  "abc",
+		AnotherField4:  "de",
+		embeddedStruct: embeddedStruct{"left out", "no"},
+	}
+	// Untagged structs count in the line length, but are not marshalled
+	if length := LineLength(target); length != 62 {
+		t.Errorf("Failed to find the line length of the embedded structs (found %v, expected 62)", length)
+	}
+	expected := strings.Repeat(" ", 35) + "0000102abc  de " + strings.Repeat(" ", 12)
+	if out, err := Marshal(target); err != nil || out != expected {
+		t.Errorf("Marshalled embedded structs as '%v' (%v)\n", out, err)
+	}
+	if length := LineLength(nestedLengthTest{}); length != 62 {
+		t.Errorf("Failed to find the line length of a nested struct (found %v, expected 62)", length)
+	}
+}
+
+func TestMarshalBool(t *testing.T) {
+	// Booleans are not written, so they never overlap other fields
+	if out, err := Marshal(boolMarshalTest{true, "abc"}); err != nil || out != "abc" {
+		t.Errorf("Marshalled bool as '%v' (%v)\n", out, err)
+	}
+}
+
+func TestMarshalWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for _, test := range []struct {
+		v       interface{}
+		warning string
+	}{
+		{struct {
+			A float64 `fixed:"0-4,x"`
+		}{12}, "non-valid format for float"},
+		{struct {
+			A float64 `fixed:"0-4,2"`
+		}{123}, "too big for output length"},
+		{struct {
+			A time.Time `fixed:"0-10,20060102"`
+		}{timeObject}, "doesn't match the field length"},
+	} {
+		buf.Reset()
+		Marshal(test.v)
+		if !strings.Contains(buf.String(), test.warning) {
+			t.Errorf("Expected a warning about '%s', got '%s'", test.warning, buf.String())
+		}
+	}
+}
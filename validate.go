@@ -0,0 +1,288 @@
+package gofixedlength
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrValidateTag = errors.New("Invalid validate tag")
+
+// FieldError describes a field value violating one of its validation rules.
+type FieldError struct {
+	Field  string // Field name, dotted for embedded structs
+	Rule   string // Violated rule, e.g. "max=100"
+	Value  string // Offending value
+	Begin  int    // Range of a `fixed` field, -1 otherwise
+	End    int
+	Column int // Index of a `csv` field, -1 otherwise or if not in the header
+}
+
+func (e *FieldError) Error() string {
+	var where string
+	switch {
+	case e.Begin >= 0:
+		where = fmt.Sprintf(" (columns %d-%d)", e.Begin, e.End)
+	case e.Column >= 0:
+		where = fmt.Sprintf(" (column %d)", e.Column)
+	}
+	return fmt.Sprintf("%s%s: value %q fails rule %s", e.Field, where, e.Value, e.Rule)
+}
+
+// ValidationErrors holds all the violations found validating a struct.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks the values of an annotated struct against the rules in
+// their `validate` tags. This should resemble:
+//
+//	type SomeType struct {
+//		Code   string `fixed:"0-3" validate:"required,oneof=ABC DEF"`
+//		Amount int    `fixed:"3-10" validate:"min=1,max=99999"`
+//		Ref    string `fixed:"10-20" validate:"len=10,regex=^[A-Z0-9]+$"`
+//	}
+//
+// Rules are separated by commas, except for regex which takes the rest of
+// the tag. "required" rejects zero values and blank strings, "min" and
+// "max" bound numeric values, "oneof" lists the accepted values separated by
+// spaces, "len" is the exact length of a string and "regex" a pattern it has
+// to match. "check" verifies the check digits of an identifier (see
+// RegisterCheckDigit), like "check=luhn" or "check=iban"; integers are
+// zero-padded to the field width first, or to the length of the
+// identifiers (see FixedLength). Blank strings are only checked by
+// "required".
+//
+// A `checkdigit:"luhn"` tag is verified as "check=luhn" when decoding, while
+// Marshal computes the check digits instead, leaving blank strings blank.
+//
+// Validate is called by Unmarshal and UnmarshalCsv after decoding, and by
+// Marshal before encoding. All the violations are returned as
+// ValidationErrors.
+func Validate(v interface{}) error {
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() != reflect.Struct {
+		return ErrInvalidTarget
+	}
+	return validateStruct(val, false)
+}
+
+// validateStruct validates a struct. When encoding, `checkdigit` tags are
+// not verified since the check digits are going to be computed.
+func validateStruct(val reflect.Value, encoding bool) error {
+	return validateCsvStruct(val, encoding, nil)
+}
+
+// validateCsvStruct validates a struct read from or written to delimited
+// records, resolving the columns of its `csv:"name=..."` fields in header.
+func validateCsvStruct(val reflect.Value, encoding bool, header []string) error {
+	var errs ValidationErrors
+	if err := validateFields(val, "", 0, header, encoding, &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateFields appends the violations found in the fields of val to errs.
+// Ranges are shifted by offset, or unknown if it is negative.
+func validateFields(val reflect.Value, prefix string, offset int, header []string, encoding bool, errs *ValidationErrors) error {
+	for i := 0; i < val.NumField(); i++ {
+		typeField := val.Type().Field(i)
+		if typeField.PkgPath != "" {
+			continue // Unexported
+		}
+		tag := typeField.Tag
+		field := val.Field(i)
+		name := prefix + typeField.Name
+
+		begin, end, column, width := -1, -1, -1, 0
+		if b, e, _, ok := fixedTag(tag); ok {
+			width = e - b
+			if offset >= 0 {
+				begin, end = b+offset, e+offset
+			}
+		} else if cField, _ := csvTag(tag); cField != "" {
+			if c, ok := csvColumn(cField, header); ok {
+				column = c
+			}
+		}
+
+		rules := splitRules(tag.Get("validate"))
+		if check := tag.Get("checkdigit"); check != "" && !encoding {
+			rules = append(rules, "check="+check)
+		}
+		for _, rule := range rules {
+			ok, err := checkRule(field, rule, width)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				*errs = append(*errs, &FieldError{
+					Field:  name,
+					Rule:   rule,
+					Value:  fmt.Sprint(reflect.Indirect(field).Interface()),
+					Begin:  begin,
+					End:    end,
+					Column: column,
+				})
+			}
+		}
+
+		// Validate embedded structs too
+		sub := reflect.Indirect(field)
+		if sub.Kind() == reflect.Struct && sub.Type() != reflect.TypeOf(time.Time{}) {
+			subOffset := offset
+			if begin >= 0 {
+				subOffset = begin
+			}
+			if err := validateFields(sub, name+".", subOffset, nil, encoding, errs); err != nil {
+				return err
+			}
+		}
+
+		// Validate the struct items of lists, found at their width in the range
+		if field.Kind() == reflect.Slice {
+			itemWidth, _ := strconv.Atoi(tag.Get("listwidth"))
+			for j := 0; j < field.Len(); j++ {
+				item := reflect.Indirect(field.Index(j))
+				if item.Kind() != reflect.Struct || item.Type() == reflect.TypeOf(time.Time{}) {
+					continue
+				}
+				itemOffset := -1
+				if itemWidth > 0 && begin >= 0 {
+					itemOffset = begin + j*itemWidth
+				}
+				if err := validateFields(item, fmt.Sprintf("%s[%d].", name, j), itemOffset, nil, encoding, errs); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// splitRules splits a validate tag into its rules.
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(rules, tag)
+		}
+		i := strings.Index(tag, ",")
+		if i < 0 {
+			return append(rules, tag)
+		}
+		rules = append(rules, tag[:i])
+		tag = tag[i+1:]
+	}
+	return rules
+}
+
+var regexCache sync.Map // Compiled regex rules
+
+// checkRule reports whether the field value satisfies rule. The width of
+// the field, 0 if it has no `fixed` range, is used to zero-pad integers.
+func checkRule(field reflect.Value, rule string, width int) (bool, error) {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return name != "required", nil
+		}
+		field = field.Elem()
+	}
+	if name == "required" {
+		if field.Kind() == reflect.String {
+			return strings.TrimSpace(field.String()) != "", nil
+		}
+		return !field.IsZero(), nil
+	}
+	if field.Kind() == reflect.String && strings.TrimSpace(field.String()) == "" {
+		return true, nil
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return false, ErrValidateTag
+		}
+		var n float64
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(field.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(field.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = field.Float()
+		default:
+			return false, ErrValidateTag
+		}
+		if name == "min" {
+			return n >= limit, nil
+		}
+		return n <= limit, nil
+	case "oneof":
+		s := fmt.Sprint(field.Interface())
+		for _, option := range strings.Fields(arg) {
+			if s == option {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "len":
+		n, err := strconv.Atoi(arg)
+		if err != nil || field.Kind() != reflect.String {
+			return false, ErrValidateTag
+		}
+		return utf8.RuneCountInString(field.String()) == n, nil
+	case "regex":
+		if field.Kind() != reflect.String {
+			return false, ErrValidateTag
+		}
+		re, ok := regexCache.Load(arg)
+		if !ok {
+			compiled, err := regexp.Compile(arg)
+			if err != nil {
+				return false, ErrValidateTag
+			}
+			re, _ = regexCache.LoadOrStore(arg, compiled)
+		}
+		return re.(*regexp.Regexp).MatchString(field.String()), nil
+	case "check":
+		c, err := lookupCheckDigit(arg)
+		if err != nil {
+			return false, err
+		}
+		var s string
+		switch field.Kind() {
+		case reflect.String:
+			s = strings.TrimSpace(field.String())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = padCheckDigit(arg, fmt.Sprint(field.Interface()), width)
+		default:
+			return false, ErrValidateTag
+		}
+		return c.Verify(s), nil
+	}
+	return false, ErrValidateTag
+}
@@ -0,0 +1,125 @@
+package gofixedlength
+
+import "testing"
+
+type validateTest struct {
+	Code   string  `fixed:"0-3" validate:"required,oneof=ABC DEF"`
+	Amount int     `fixed:"3-8" validate:"min=1,max=50000"`
+	Ref    string  `fixed:"8-14" validate:"len=6,regex=^[A-Z]{2}[0-9,]+$"`
+	Rate   float64 `fixed:"14-18" validate:"max=9.9"`
+}
+
+type validateCsvTest struct {
+	Name  string `csv:"0" validate:"required"`
+	Count uint   `csv:"1" validate:"oneof=1 2 3"`
+}
+
+func TestValidateUnmarshal(t *testing.T) {
+	var out validateTest
+	if err := Unmarshal("ABC00100XY12,401.5", &out); err != nil {
+		t.Errorf("Valid record rejected: %v", err)
+	}
+
+	err := Unmarshal("XYZ00000xy1234", &out)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	expected := []struct {
+		field      string
+		rule       string
+		begin, end int
+	}{
+		{"Code", "oneof=ABC DEF", 0, 3},
+		{"Amount", "min=1", 3, 8},
+		{"Ref", "regex=^[A-Z]{2}[0-9,]+$", 8, 14},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("Found %d violations: %v", len(errs), errs)
+	}
+	for i, e := range expected {
+		if errs[i].Field != e.field || errs[i].Rule != e.rule || errs[i].Begin != e.begin || errs[i].End != e.end {
+			t.Errorf("Violation %d reported as %+v", i, errs[i])
+		}
+	}
+}
+
+func TestValidateUnmarshalCsv(t *testing.T) {
+	var out validateCsvTest
+	err := UnmarshalCsv(",4", ",", &out)
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected two violations, got %v", err)
+	}
+	if errs[0].Field != "Name" || errs[0].Rule != "required" || errs[0].Column != 0 || errs[0].Begin != -1 {
+		t.Errorf("Violation reported as %+v", errs[0])
+	}
+	if errs[1].Field != "Count" || errs[1].Column != 1 {
+		t.Errorf("Violation reported as %+v", errs[1])
+	}
+}
+
+func TestValidateNamedColumns(t *testing.T) {
+	opts := CsvOptions{Header: []string{"Amount", "AccountID", "Note"}}
+	var out csvStreamTest
+	err := UnmarshalCsvOptions("1,,x", opts, &out)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Field != "Account" || errs[0].Column != 1 {
+		t.Errorf("Expected a violation in column 1, got %v", err)
+	}
+	_, err = MarshalCsvOptions(out, opts)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Column != 1 {
+		t.Errorf("Expected a violation in column 1, got %v", err)
+	}
+	if err := Validate(out); err.(ValidationErrors)[0].Column != -1 {
+		t.Errorf("Expected no column without a header, got %v", err)
+	}
+}
+
+func TestValidateMarshal(t *testing.T) {
+	out, err := Marshal(validateTest{"DEF", 60000, "AB1234", 1.5})
+	if _, ok := err.(ValidationErrors); !ok || out != "" {
+		t.Errorf("Expected validation to fail, got '%s' and %v", out, err)
+	}
+	if err := Validate(validateTest{"DEF", 1, "AB1234", 1.5}); err != nil {
+		t.Errorf("Valid struct rejected: %v", err)
+	}
+	if err := Validate(validateTest{Code: "ABC", Amount: 1, Ref: "AB", Rate: 1}); err == nil {
+		t.Errorf("Expected len rule to fail")
+	}
+	if err := Validate(&struct {
+		A int `validate:"between=1"`
+	}{}); err != ErrValidateTag {
+		t.Errorf("Expected ErrValidateTag, got %v", err)
+	}
+}
+
+type validateItem struct {
+	Code   string `fixed:"0-2" csv:"0" validate:"oneof=AB CD"`
+	Amount int    `fixed:"2-5" csv:"1"`
+}
+
+type validateListTest struct {
+	Items []validateItem `fixed:"0-15" csv:"0" listwidth:"5"`
+}
+
+func TestValidateListItems(t *testing.T) {
+	var out validateListTest
+	err := Unmarshal("AB001CD002XY003", &out)
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 1 || errs[0].Field != "Items[2].Code" || errs[0].Begin != 10 || errs[0].End != 12 {
+		t.Errorf("Expected a violation in Items[2].Code, got %v", err)
+	}
+	if len(out.Items) != 3 || out.Items[2].Amount != 3 {
+		t.Errorf("Items parsed as %+v", out.Items)
+	}
+
+	err = UnmarshalCsv("AB001XY002", ",", &out)
+	if errs, ok := err.(ValidationErrors); !ok || len(errs) != 1 || errs[0].Field != "Items[1].Code" {
+		t.Errorf("Expected a violation in Items[1].Code, got %v", err)
+	}
+
+	line, err := Marshal(validateListTest{[]validateItem{{"XY", 1}}})
+	if errs, ok := err.(ValidationErrors); !ok || line != "" || errs[0].Field != "Items[0].Code" {
+		t.Errorf("Expected a violation in Items[0].Code, got '%s' and %v", line, err)
+	}
+}